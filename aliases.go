@@ -0,0 +1,358 @@
+package structflag
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ogier/pflag"
+	reflection "github.com/ungerik/go-reflection"
+	"gopkg.in/yaml.v3"
+)
+
+// aliasValue wraps the pflag.Value of a flag
+// that is an alias for another flag.
+// Aliases are not shown separately in the usage output.
+type aliasValue struct {
+	pflag.Value
+}
+
+func (a *aliasValue) IsBoolFlag() bool {
+	return isBoolFlag(a.Value)
+}
+
+// fieldFlagAliases returns the alias flag names following
+// the first name in the comma separated NameTag of a field
+// without NameFunc applied.
+func fieldFlagAliases(field reflect.StructField) []string {
+	names := tagList(field, NameTag)
+	if len(names) < 2 {
+		return nil
+	}
+	return names[1:]
+}
+
+// hasAliases returns if t or one of its nested
+// struct types has a field with alias flag names.
+func hasAliases(t reflect.Type) bool {
+	for _, f := range reflection.FlatExportedNamedStructFields(t, "") {
+		if len(fieldFlagAliases(f.Field)) > 0 {
+			return true
+		}
+		ft := reflection.DerefType(f.Field.Type)
+		if ft.Kind() == reflect.Slice {
+			ft = reflection.DerefType(ft.Elem())
+		}
+		if isNestedStructType(ft) && hasAliases(ft) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileKeyNames returns the flag names of a field
+// that are accepted as alternative keys in configuration files.
+func fileKeyNames(field reflect.StructField) []string {
+	name, ok := fieldFlagName(field)
+	if !ok {
+		return nil
+	}
+	return append([]string{name}, fieldFlagAliases(field)...)
+}
+
+// renameAliasKeys renames keys of m that match a flag name
+// or alias of a field of the struct type t to the key of the field
+// returned by fieldKey, if m has no value for that key.
+// Nested structs are renamed recursively.
+func renameAliasKeys(m map[string]interface{}, t reflect.Type, fieldKey func(reflect.StructField) string) {
+	for _, f := range reflection.FlatExportedNamedStructFields(t, "") {
+		key := fieldKey(f.Field)
+		if key == "" {
+			continue
+		}
+		if _, ok := m[key]; !ok {
+			for _, alias := range fileKeyNames(f.Field) {
+				if value, ok := m[alias]; ok && alias != key {
+					m[key] = value
+					delete(m, alias)
+					break
+				}
+			}
+		}
+		ft := reflection.DerefType(f.Field.Type)
+		if nested, ok := m[key].(map[string]interface{}); ok && isNestedStructType(ft) {
+			renameAliasKeys(nested, ft, fieldKey)
+		}
+		// Slices of structs, like arrays of tables in TOML
+		if ft.Kind() == reflect.Slice && isNestedStructType(reflection.DerefType(ft.Elem())) {
+			elemType := reflection.DerefType(ft.Elem())
+			switch elems := m[key].(type) {
+			case []map[string]interface{}:
+				for _, nested := range elems {
+					renameAliasKeys(nested, elemType, fieldKey)
+				}
+			case []interface{}:
+				for _, elem := range elems {
+					if nested, ok := elem.(map[string]interface{}); ok {
+						renameAliasKeys(nested, elemType, fieldKey)
+					}
+				}
+			}
+		}
+	}
+}
+
+// jsonFieldKey returns the JSON object key of a struct field
+// or an empty string if the field is ignored by encoding/json.
+func jsonFieldKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// renameJSONAliases renames the object keys in the JSON data
+// that match flag aliases of the fields of the struct type t.
+func renameJSONAliases(data []byte, t reflect.Type) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var m map[string]interface{}
+	err := decoder.Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+	renameAliasKeys(m, reflection.DerefType(t), jsonFieldKey)
+	return json.Marshal(m)
+}
+
+// yamlFieldKey returns the YAML mapping key of a struct field
+// or an empty string if the field is ignored by gopkg.in/yaml.v3.
+func yamlFieldKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// renameYAMLAliases renames the mapping keys of the YAML node
+// that match flag names or aliases of the fields of the struct type t.
+// Only the keys are changed, so all values keep their YAML types.
+// Nested structs are renamed recursively.
+func renameYAMLAliases(node *yaml.Node, t reflect.Type) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, content := range node.Content {
+			renameYAMLAliases(content, t)
+		}
+		return
+	case yaml.MappingNode:
+	default:
+		return
+	}
+	// keys maps the mapping keys to their index in node.Content
+	keys := make(map[string]int)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys[node.Content[i].Value] = i
+	}
+	for _, f := range reflection.FlatExportedNamedStructFields(reflection.DerefType(t), "") {
+		key := yamlFieldKey(f.Field)
+		if key == "" {
+			continue
+		}
+		i, ok := keys[key]
+		if !ok {
+			for _, alias := range fileKeyNames(f.Field) {
+				if j, found := keys[alias]; found && alias != key {
+					node.Content[j].Value = key
+					delete(keys, alias)
+					keys[key] = j
+					i, ok = j, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		value := node.Content[i+1]
+		ft := reflection.DerefType(f.Field.Type)
+		if isNestedStructType(ft) {
+			renameYAMLAliases(value, ft)
+		}
+		// Sequences of structs
+		if ft.Kind() == reflect.Slice && isNestedStructType(reflection.DerefType(ft.Elem())) && value.Kind == yaml.SequenceNode {
+			for _, elem := range value.Content {
+				renameYAMLAliases(elem, reflection.DerefType(ft.Elem()))
+			}
+		}
+	}
+}
+
+// tomlFieldKey returns the TOML key of a struct field
+// or an empty string if the field is ignored by github.com/BurntSushi/toml.
+func tomlFieldKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// renameTOMLAliases renames the keys in the TOML data
+// that match flag names or aliases of the fields of the struct type t.
+func renameTOMLAliases(data []byte, t reflect.Type) ([]byte, error) {
+	var m map[string]interface{}
+	err := toml.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+	renameAliasKeys(m, reflection.DerefType(t), tomlFieldKey)
+	var buf bytes.Buffer
+	err = toml.NewEncoder(&buf).Encode(m)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xmlFieldName returns the local XML element name of a struct field
+// without name space or an empty string if the field is not a simple element.
+func xmlFieldName(field reflect.StructField) string {
+	name, flags, _ := strings.Cut(field.Tag.Get("xml"), ",")
+	if i := strings.LastIndexByte(name, ' '); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "-" || strings.Contains(name, ">") || (flags != "" && flags != "omitempty") {
+		return ""
+	}
+	if field.Name == "XMLName" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// renameXMLAliases renames the elements in the XML data
+// that match flag aliases of the fields of the struct type t.
+// Only the names of renamed start and end tags are replaced,
+// all other bytes of data are kept unchanged.
+func renameXMLAliases(data []byte, t reflect.Type) ([]byte, error) {
+	var (
+		buf     bytes.Buffer
+		decoder = xml.NewDecoder(bytes.NewReader(data))
+		// types of the currently open elements,
+		// nil for elements without struct fields to rename
+		types []reflect.Type
+		// new local names of the currently open elements,
+		// empty for elements that are not renamed
+		renames []string
+		// data up to copied has been written to buf
+		copied int
+	)
+	// renameTag replaces the local name of the tag
+	// that starts with '<' at offset in data with newLocal
+	renameTag := func(offset int64, name xml.Name, newLocal string, end bool) {
+		pos := int(offset) + 1
+		if end {
+			if pos >= len(data) || data[pos] != '/' {
+				return
+			}
+			pos++
+		}
+		if name.Space != "" {
+			pos += len(name.Space) + 1
+		}
+		if pos > len(data) || !bytes.HasPrefix(data[pos:], []byte(name.Local)) {
+			return
+		}
+		buf.Write(data[copied:pos])
+		buf.WriteString(newLocal)
+		copied = pos + len(name.Local)
+	}
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := token.(type) {
+		case xml.StartElement:
+			var (
+				elemType reflect.Type
+				rename   string
+			)
+			if len(types) == 0 {
+				elemType = reflection.DerefType(t)
+			} else if parent := types[len(types)-1]; parent != nil {
+				field, ok := xmlAliasField(parent, tok.Name.Local)
+				if ok {
+					if name := xmlFieldName(field); name != tok.Name.Local {
+						rename = name
+						renameTag(offset, tok.Name, rename, false)
+					}
+					if ft := reflection.DerefType(field.Type); isNestedStructType(ft) {
+						elemType = ft
+					}
+				}
+			}
+			types = append(types, elemType)
+			renames = append(renames, rename)
+		case xml.EndElement:
+			if len(renames) == 0 {
+				break
+			}
+			// Self closing elements have no end tag
+			// and don't advance the input offset
+			if rename := renames[len(renames)-1]; rename != "" && decoder.InputOffset() > offset {
+				renameTag(offset, tok.Name, rename, true)
+			}
+			types = types[:len(types)-1]
+			renames = renames[:len(renames)-1]
+		}
+	}
+	if copied == 0 {
+		return data, nil
+	}
+	buf.Write(data[copied:])
+	return buf.Bytes(), nil
+}
+
+// xmlAliasField returns the field of the struct type t
+// with the XML element name or a flag name or alias matching name.
+func xmlAliasField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for _, f := range reflection.FlatExportedNamedStructFields(t, "") {
+		elemName := xmlFieldName(f.Field)
+		if elemName == "" {
+			continue
+		}
+		if elemName == name {
+			return f.Field, true
+		}
+		for _, alias := range fileKeyNames(f.Field) {
+			if alias == name {
+				return f.Field, true
+			}
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,285 @@
+package structflag
+
+import (
+	"reflect"
+	"testing"
+)
+
+type yamlAliasDatabase struct {
+	Host string `flag:"host,hostname" yaml:"host"`
+	Port int
+}
+
+type yamlAliasConfig struct {
+	Version  string `yaml:"version"`
+	Date     string `yaml:"date"`
+	Mode     string `yaml:"mode"`
+	Zip      string `yaml:"zip"`
+	MaxConns int    `flag:"max-conns,connections"`
+	DB       yamlAliasDatabase
+	Replicas []yamlAliasDatabase `yaml:"replicas"`
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    yamlAliasConfig
+		wantErr bool
+	}{
+		{
+			name: "untagged scalars into strings",
+			data: "version: 1.10\ndate: 2024-01-02\nmode: 0644\nzip: 01234\n",
+			want: yamlAliasConfig{Version: "1.10", Date: "2024-01-02", Mode: "0644", Zip: "01234"},
+		},
+		{
+			name: "flag name and alias keys",
+			data: "max-conns: 5\ndb:\n  hostname: h\n  Port: 80\n",
+			want: yamlAliasConfig{MaxConns: 5, DB: yamlAliasDatabase{Host: "h", Port: 80}},
+		},
+		{
+			name: "alias key",
+			data: "connections: 5\n",
+			want: yamlAliasConfig{MaxConns: 5},
+		},
+		{
+			name: "field key wins over alias",
+			data: "maxconns: 1\nconnections: 5\n",
+			want: yamlAliasConfig{MaxConns: 1},
+		},
+		{
+			name: "sequence of structs",
+			data: "replicas:\n  - hostname: a\n  - host: b\n    port: 2\n",
+			want: yamlAliasConfig{Replicas: []yamlAliasDatabase{{Host: "a"}, {Host: "b", Port: 2}}},
+		},
+		{
+			name: "empty document",
+			data: "",
+			want: yamlAliasConfig{},
+		},
+		{
+			name:    "invalid",
+			data:    "version: [\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got yamlAliasConfig
+			err := unmarshalYAML([]byte(tt.data), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("unmarshalYAML() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unmarshalYAML() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type xmlAliasDatabase struct {
+	Host string `flag:"host,hostname" xml:"host"`
+	Port int    `xml:"port"`
+}
+
+type xmlAliasConfig struct {
+	Name     string           `xml:"name,attr"`
+	MaxConns int              `flag:"max-conns,connections" xml:"maxConns"`
+	DB       xmlAliasDatabase `xml:"db"`
+}
+
+func TestRenameXMLAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "unchanged",
+			data: `<config><maxConns>1</maxConns><db><host>h</host></db></config>`,
+			want: `<config><maxConns>1</maxConns><db><host>h</host></db></config>`,
+		},
+		{
+			name: "alias",
+			data: `<config><connections>5</connections></config>`,
+			want: `<config><maxConns>5</maxConns></config>`,
+		},
+		{
+			name: "flag name",
+			data: `<config><max-conns>5</max-conns></config>`,
+			want: `<config><maxConns>5</maxConns></config>`,
+		},
+		{
+			name: "nested",
+			data: `<config><db><hostname>h</hostname><port>1</port></db></config>`,
+			want: `<config><db><host>h</host><port>1</port></db></config>`,
+		},
+		{
+			name: "name space",
+			data: `<c:config xmlns:c="urn:x"><c:connections>5</c:connections></c:config>`,
+			want: `<c:config xmlns:c="urn:x"><c:maxConns>5</c:maxConns></c:config>`,
+		},
+		{
+			name: "self closing",
+			data: `<config><hostname/><db><hostname a="1"/></db></config>`,
+			want: `<config><hostname/><db><host a="1"/></db></config>`,
+		},
+		{
+			name: "attributes and white space",
+			data: "<?xml version=\"1.0\"?>\n<config name=\"connections\">\n  <!-- connections -->\n  <connections a=\"1\" >5</connections >\n</config>\n",
+			want: "<?xml version=\"1.0\"?>\n<config name=\"connections\">\n  <!-- connections -->\n  <maxConns a=\"1\" >5</maxConns >\n</config>\n",
+		},
+		{
+			name: "unknown parent",
+			data: `<config><other><connections>1</connections></other></config>`,
+			want: `<config><other><connections>1</connections></other></config>`,
+		},
+		{
+			name:    "invalid",
+			data:    `<config><connections`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.data)
+			got, err := renameXMLAliases(data, reflect.TypeOf(&xmlAliasConfig{}))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("renameXMLAliases() = %s, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("renameXMLAliases() = %s, want %s", got, tt.want)
+			}
+			if tt.data == tt.want && &got[0] != &data[0] {
+				t.Error("renameXMLAliases() did not return unchanged data as is")
+			}
+		})
+	}
+}
+
+type fileAliasConfig struct {
+	MaxConns int              `flag:"max-conns,connections" json:"maxConns" toml:"maxConns"`
+	DB       xmlAliasDatabase `json:"db" toml:"db"`
+}
+
+func TestUnmarshalAliases(t *testing.T) {
+	tests := []struct {
+		name      string
+		unmarshal func([]byte, interface{}) error
+		data      string
+		want      fileAliasConfig
+		wantErr   bool
+	}{
+		{
+			name:      "JSON field keys",
+			unmarshal: unmarshalJSON,
+			data:      `{"maxConns": 1, "db": {"Host": "h"}}`,
+			want:      fileAliasConfig{MaxConns: 1, DB: xmlAliasDatabase{Host: "h"}},
+		},
+		{
+			name:      "JSON aliases",
+			unmarshal: unmarshalJSON,
+			data:      `{"connections": 5, "db": {"hostname": "h", "Port": 2}}`,
+			want:      fileAliasConfig{MaxConns: 5, DB: xmlAliasDatabase{Host: "h", Port: 2}},
+		},
+		{
+			name:      "JSON field key wins over alias",
+			unmarshal: unmarshalJSON,
+			data:      `{"max-conns": 5, "maxConns": 1}`,
+			want:      fileAliasConfig{MaxConns: 1},
+		},
+		{
+			name:      "JSON invalid",
+			unmarshal: unmarshalJSON,
+			data:      `{"connections": }`,
+			wantErr:   true,
+		},
+		{
+			name:      "TOML aliases",
+			unmarshal: unmarshalTOML,
+			data:      "max-conns = 5\n[db]\nhostname = \"h\"\n",
+			want:      fileAliasConfig{MaxConns: 5, DB: xmlAliasDatabase{Host: "h"}},
+		},
+		{
+			name:      "TOML invalid",
+			unmarshal: unmarshalTOML,
+			data:      "connections = \n",
+			wantErr:   true,
+		},
+		{
+			name:      "XML aliases",
+			unmarshal: unmarshalXML,
+			data:      `<config><connections>5</connections><DB><hostname>h</hostname></DB></config>`,
+			want:      fileAliasConfig{MaxConns: 5, DB: xmlAliasDatabase{Host: "h"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got fileAliasConfig
+			err := tt.unmarshal([]byte(tt.data), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("unmarshal = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("unmarshal = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliasFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want fileAliasConfig
+	}{
+		{name: "name", args: []string{"--max-conns=1"}, want: fileAliasConfig{MaxConns: 1}},
+		{name: "alias", args: []string{"--connections=5"}, want: fileAliasConfig{MaxConns: 5}},
+		{name: "last wins", args: []string{"--connections=5", "--max-conns=1"}, want: fileAliasConfig{MaxConns: 1}},
+		{name: "nested alias", args: []string{"--DB.hostname=h"}, want: fileAliasConfig{DB: xmlAliasDatabase{Host: "h"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			config, _, err := Bind[fileAliasConfig](WithArgs(tt.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *config != tt.want {
+				t.Errorf("Bind() = %+v, want %+v", *config, tt.want)
+			}
+		})
+	}
+
+	resetFlags(t)
+	var config fileAliasConfig
+	err := StructVarE(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lookupFlag(t, "connections").Value.(*aliasValue); !ok {
+		t.Error("--connections is not an alias flag")
+	}
+	if usage := lookupFlag(t, "max-conns").Usage; usage != "(aliases: --connections)" {
+		t.Errorf("usage of --max-conns = %q", usage)
+	}
+}
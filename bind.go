@@ -0,0 +1,82 @@
+package structflag
+
+import (
+	"errors"
+	"os"
+	"reflect"
+)
+
+// BindOption configures Bind
+type BindOption func(*bindOptions)
+
+type bindOptions struct {
+	filename     string
+	optionalFile bool
+	args         []string
+}
+
+// WithFile loads the configuration file filename
+// before parsing the command line arguments.
+// It is an error if the file does not exist.
+func WithFile(filename string) BindOption {
+	return func(o *bindOptions) {
+		o.filename = filename
+		o.optionalFile = false
+	}
+}
+
+// WithOptionalFile loads the configuration file filename
+// before parsing the command line arguments
+// if the file exists.
+func WithOptionalFile(filename string) BindOption {
+	return func(o *bindOptions) {
+		o.filename = filename
+		o.optionalFile = true
+	}
+}
+
+// WithArgs parses args instead of os.Args[1:]
+func WithArgs(args ...string) BindOption {
+	return func(o *bindOptions) {
+		o.args = append([]string{}, args...)
+	}
+}
+
+// Bind allocates a new T, sets the values of its DefaultTag,
+// loads the configuration file given by WithFile or WithOptionalFile
+// and then parses the command line like LoadFileAndParseCommandLine.
+// The fields of T are defined as flags on new Flags
+// instead of the package level flags used by StructVar,
+// so Bind can be called multiple times with the same flag names.
+// T must be a struct type.
+// Returns the new struct and the remaining non flag arguments.
+func Bind[T any](options ...BindOption) (*T, []string, error) {
+	var opts bindOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	config := new(T)
+	err := checkStructPtr(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	bindFlags := NewFlags()
+	errs := structVar(config, "", nil, newFlagRelations(reflect.TypeOf(config)), nil, bindFlags, tagDefaults, nil)
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+	args, err := loadFileAndParse(opts.filename, config, opts.args, bindFlags)
+	if err != nil && !(opts.optionalFile && errors.Is(err, os.ErrNotExist)) {
+		return nil, nil, err
+	}
+	return config, args, nil
+}
+
+// MustBind is like Bind but panics on error
+func MustBind[T any](options ...BindOption) (*T, []string) {
+	config, args, err := Bind[T](options...)
+	if err != nil {
+		panic(err)
+	}
+	return config, args
+}
@@ -0,0 +1,126 @@
+package structflag
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ogier/pflag"
+)
+
+type bindConfig struct {
+	Name string   `flag:"name" json:"name" default:"app"`
+	Port int      `flag:"port" json:"port" default:"80"`
+	Tags []string `flag:"tags" json:"tags"`
+}
+
+func TestBind(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "config.json")
+	err := os.WriteFile(existing, []byte(`{"name": "file", "tags": ["a"]}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.json")
+
+	tests := []struct {
+		name        string
+		options     []BindOption
+		want        bindConfig
+		wantArgs    []string
+		wantErr     bool
+		wantMissing bool
+	}{
+		{
+			name:    "defaults",
+			options: []BindOption{WithArgs()},
+			want:    bindConfig{Name: "app", Port: 80},
+		},
+		{
+			name:     "args",
+			options:  []BindOption{WithArgs("--port=8080", "x", "y")},
+			want:     bindConfig{Name: "app", Port: 8080},
+			wantArgs: []string{"x", "y"},
+		},
+		{
+			name:    "file",
+			options: []BindOption{WithFile(existing), WithArgs("--port=1")},
+			want:    bindConfig{Name: "file", Port: 1, Tags: []string{"a"}},
+		},
+		{
+			name:        "missing file",
+			options:     []BindOption{WithFile(missing), WithArgs()},
+			wantErr:     true,
+			wantMissing: true,
+		},
+		{
+			name:    "missing optional file",
+			options: []BindOption{WithOptionalFile(missing), WithArgs("--name=x")},
+			want:    bindConfig{Name: "x", Port: 80},
+		},
+		{
+			name:    "last file option wins",
+			options: []BindOption{WithOptionalFile(missing), WithFile(existing), WithArgs()},
+			want:    bindConfig{Name: "file", Port: 80, Tags: []string{"a"}},
+		},
+		{
+			name:    "unknown flag",
+			options: []BindOption{WithArgs("--unknown")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			config, args, err := Bind[bindConfig](tt.options...)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Bind() = %+v, want error", config)
+				}
+				if isMissing := errors.Is(err, os.ErrNotExist); isMissing != tt.wantMissing {
+					t.Errorf("Bind() error %q is missing file = %t, want %t", err, isMissing, tt.wantMissing)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*config, tt.want) {
+				t.Errorf("Bind() = %+v, want %+v", *config, tt.want)
+			}
+			if (len(args) > 0 || len(tt.wantArgs) > 0) && !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Bind() args = %q, want %q", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestBindRepeated(t *testing.T) {
+	resetFlags(t)
+	// Bind must not define flags on the package level flags
+	// so that the same flag names can be bound again
+	for i := 0; i < 3; i++ {
+		config, _, err := Bind[bindConfig](WithArgs("--name=x"))
+		if err != nil {
+			t.Fatalf("Bind() call %d error: %s", i, err)
+		}
+		if config.Name != "x" {
+			t.Errorf("Bind() call %d Name = %q", i, config.Name)
+		}
+	}
+	if getOrCreateFlags().(*pflag.FlagSet).Lookup("name") != nil {
+		t.Error("Bind() defined --name on the package level flags")
+	}
+
+	if _, _, err := Bind[int](); err == nil {
+		t.Error("Bind[int]() want error")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("MustBind() with unknown flag did not panic")
+		}
+	}()
+	MustBind[bindConfig](WithArgs("--unknown"))
+}
@@ -0,0 +1,113 @@
+package structflag
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+
+	reflection "github.com/ungerik/go-reflection"
+)
+
+// fieldEnvName returns the environment variable name of a field
+// with the flag name from its EnvTag or derived from EnvPrefix.
+// Returns an empty string if the field has no environment variable.
+func fieldEnvName(field reflect.StructField, name string) string {
+	env, hasEnv := field.Tag.Lookup(EnvTag)
+	switch {
+	case env == "-":
+		return ""
+	case hasEnv && env != "":
+		return env
+	case EnvPrefix == "":
+		return ""
+	}
+	return EnvPrefix + "_" + envVarName(name)
+}
+
+// envVarName returns the flag name in upper case with all other
+// characters than letters and digits replaced by underscores.
+func envVarName(name string) string {
+	return strings.Map(
+		func(r rune) rune {
+			if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return '_'
+			}
+			return unicode.ToUpper(r)
+		},
+		name,
+	)
+}
+
+// setEnvFlags sets the flags of the fields of the struct type t
+// that were defined in flags to the values of
+// the existing environment variables of the fields.
+func setEnvFlags(t reflect.Type, flags Flags) error {
+	setter, ok := flags.(interface {
+		Set(name, value string) error
+	})
+	if !ok {
+		return nil
+	}
+	var errs FieldErrors
+	visitFlagFields(reflection.DerefType(t), "", func(field reflect.StructField, name string) {
+		env := fieldEnvName(field, name)
+		if env == "" {
+			return
+		}
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			return
+		}
+		err := setter.Set(name, value)
+		if err != nil {
+			err = fmt.Errorf("invalid value %q of environment variable %s: %w", maskedValue(field, value), env, err)
+			errs = append(errs, newFieldError(field, name, "", err))
+		}
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// visitFlagFields calls visit with the flag name for every field
+// of the struct type t and its nested struct types that defines a flag.
+func visitFlagFields(t reflect.Type, namePrefix string, visit func(field reflect.StructField, name string)) {
+	for _, f := range reflection.FlatExportedNamedStructFields(t, "") {
+		fieldName, ok := fieldFlagName(f.Field)
+		if !ok {
+			continue
+		}
+		if ft := reflection.DerefType(f.Field.Type); isNestedStructType(ft) {
+			visitFlagFields(ft, namePrefix+nestedNamePrefix(f.Field, fieldName), visit)
+			continue
+		}
+		visit(f.Field, NameFunc(namePrefix+fieldName))
+	}
+}
+
+// LoadEnv sets the fields of structPtr from the environment variables
+// defined by EnvTag or EnvPrefix. The values are parsed like flags.
+// Fields without a set environment variable keep their value.
+// If structPtr was passed to StructVar, then the loaded values
+// count as set for RequiredTag, XorTag and RequiresTag in Parse.
+func LoadEnv(structPtr interface{}) error {
+	return loadEnv(structPtr, boundValueSources(structPtr))
+}
+
+// loadEnv implements LoadEnv and sets the sources
+// of the flags set from environment variables in sources.
+func loadEnv(structPtr interface{}, sources *valueSources) error {
+	err := checkStructPtr(structPtr)
+	if err != nil {
+		return err
+	}
+	envFlags := NewFlags()
+	errs := structVar(structPtr, "", nil, newFlagRelations(reflect.TypeOf(structPtr)), sources, envFlags, fieldValueDefaults, nil)
+	if len(errs) > 0 {
+		return errs
+	}
+	return setEnvFlags(reflect.TypeOf(structPtr), envFlags)
+}
@@ -0,0 +1,171 @@
+package structflag
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFieldEnvName(t *testing.T) {
+	type config struct {
+		Tagged   string `env:"MY_TAGGED"`
+		Untagged string
+		Empty    string `env:""`
+		Ignored  string `env:"-"`
+	}
+	typ := reflect.TypeOf(config{})
+	tests := []struct {
+		field  string
+		flag   string
+		prefix string
+		want   string
+	}{
+		{field: "Tagged", flag: "tagged", want: "MY_TAGGED"},
+		{field: "Tagged", flag: "tagged", prefix: "APP", want: "MY_TAGGED"},
+		{field: "Untagged", flag: "untagged", want: ""},
+		{field: "Untagged", flag: "untagged", prefix: "APP", want: "APP_UNTAGGED"},
+		{field: "Untagged", flag: "db.max-conns", prefix: "APP", want: "APP_DB_MAX_CONNS"},
+		{field: "Untagged", flag: "Größe", prefix: "APP", want: "APP_GR__E"},
+		{field: "Empty", flag: "empty", prefix: "APP", want: "APP_EMPTY"},
+		{field: "Ignored", flag: "ignored", prefix: "APP", want: ""},
+	}
+	for _, tt := range tests {
+		EnvPrefix = tt.prefix
+		field, _ := typ.FieldByName(tt.field)
+		if got := fieldEnvName(field, tt.flag); got != tt.want {
+			t.Errorf("fieldEnvName(%s, %q) with EnvPrefix %q = %q, want %q", tt.field, tt.flag, tt.prefix, got, tt.want)
+		}
+	}
+	EnvPrefix = ""
+}
+
+type envDatabase struct {
+	Host string `flag:"host" json:"host"`
+}
+
+type envConfig struct {
+	Name string      `flag:"name" json:"name" default:"default"`
+	Port int         `flag:"port" json:"port" env:"STRUCTFLAG_TEST_PORT"`
+	Skip string      `flag:"skip" json:"skip" env:"-"`
+	DB   envDatabase `flag:"db" json:"db"`
+}
+
+func TestEnvPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		file    string
+		args    []string
+		want    envConfig
+		wantErr bool
+	}{
+		{
+			name: "default",
+			want: envConfig{Name: "default"},
+		},
+		{
+			name: "file over default",
+			file: `{"name": "file", "port": 1}`,
+			want: envConfig{Name: "file", Port: 1},
+		},
+		{
+			name: "env over file",
+			env:  map[string]string{"APP_NAME": "env", "STRUCTFLAG_TEST_PORT": "2", "APP_DB_HOST": "h"},
+			file: `{"name": "file", "port": 1}`,
+			want: envConfig{Name: "env", Port: 2, DB: envDatabase{Host: "h"}},
+		},
+		{
+			name: "flags over env",
+			env:  map[string]string{"APP_NAME": "env", "STRUCTFLAG_TEST_PORT": "2"},
+			file: `{"name": "file"}`,
+			args: []string{"--name=flag"},
+			want: envConfig{Name: "flag", Port: 2},
+		},
+		{
+			name: "ignored env",
+			env:  map[string]string{"APP_SKIP": "env"},
+			want: envConfig{Name: "default"},
+		},
+		{
+			name: "empty env",
+			env:  map[string]string{"APP_NAME": ""},
+			want: envConfig{},
+		},
+		{
+			name:    "invalid env",
+			env:     map[string]string{"STRUCTFLAG_TEST_PORT": "x"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			EnvPrefix = "APP"
+			defer func() { EnvPrefix = "" }()
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			options := []BindOption{WithArgs(tt.args...)}
+			if tt.file != "" {
+				filename := filepath.Join(t.TempDir(), "config.json")
+				err := os.WriteFile(filename, []byte(tt.file), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+				options = append(options, WithFile(filename))
+			}
+			config, _, err := Bind[envConfig](options...)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Bind() = %+v, want error", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *config != tt.want {
+				t.Errorf("Bind() = %+v, want %+v", *config, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	resetFlags(t)
+	t.Setenv("STRUCTFLAG_TEST_PORT", "3")
+	config := envConfig{Name: "keep"}
+	err := LoadEnv(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (envConfig{Name: "keep", Port: 3}); config != want {
+		t.Errorf("LoadEnv() = %+v, want %+v", config, want)
+	}
+	if err := LoadEnv(config); err == nil {
+		t.Error("LoadEnv() with struct value, want error")
+	}
+
+	resetFlags(t)
+	EnvPrefix = "APP"
+	defer func() { EnvPrefix = "" }()
+	err = StructVarE(&envConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		flag string
+		want string
+	}{
+		{"name", "(env: APP_NAME)"},
+		{"port", "(env: STRUCTFLAG_TEST_PORT)"},
+		{"skip", ""},
+		{"db.host", "(env: APP_DB_HOST)"},
+	}
+	for _, tt := range tests {
+		if got := lookupFlag(t, tt.flag).Usage; got != tt.want {
+			t.Errorf("usage of --%s = %q, want %q", tt.flag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package structflag
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FieldError describes why a struct field
+// could not be defined as flag or why its value
+// failed a validation, required or relationship check.
+type FieldError struct {
+	// Field is the name of the struct field
+	Field string
+	// Flag is the name of the flag
+	Flag string
+	// Tag is the struct tag that caused the error,
+	// or an empty string if the error is not caused by a tag
+	Tag string
+	// TagValue is the value of Tag
+	TagValue string
+	// Err is the reason for the error
+	Err error
+}
+
+func newFieldError(field reflect.StructField, flag, tag string, err error) *FieldError {
+	return &FieldError{
+		Field:    field.Name,
+		Flag:     flag,
+		Tag:      tag,
+		TagValue: field.Tag.Get(tag),
+		Err:      err,
+	}
+}
+
+func (e *FieldError) Error() string {
+	if e.Tag == "" {
+		return fmt.Sprintf("field %s (flag %s): %s", e.Field, e.Flag, e.Err)
+	}
+	return fmt.Sprintf("field %s (flag %s) %s tag %q: %s", e.Field, e.Flag, e.Tag, e.TagValue, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func errUnsupportedType(t reflect.Type) error {
+	return fmt.Errorf("unsupported type %s", t)
+}
+
+// FieldErrors is an error aggregating the
+// errors of multiple struct fields.
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
@@ -1,36 +1,101 @@
 package structflag
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
 	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
-// LoadFile loads a struct from a JSON or XML file.
+// LoadFile loads a struct from a JSON, XML, YAML, TOML,
+// INI, .env or Java .properties file.
 // The file type is determined by the file extension.
+// Values of fields with an EnumTag are checked after loading.
+// If structPtr was passed to StructVar, then the loaded values
+// count as set for RequiredTag, XorTag and RequiresTag in Parse.
 func LoadFile(filename string, structPtr interface{}) error {
+	return loadFile(filename, structPtr, boundValueSources(structPtr))
+}
+
+// loadFile implements LoadFile and sets fileSource in sources
+// for the flags of the loaded fields if sources is not nil.
+func loadFile(filename string, structPtr interface{}, sources *valueSources) error {
 	filename = filepath.Clean(filename)
-	// Load and unmarshal struct from file
-	ext := strings.ToLower(filepath.Ext(filename))
+	var (
+		ext       = strings.ToLower(filepath.Ext(filename))
+		unmarshal func(data []byte, structPtr interface{}) error
+		// INI, .env and .properties files are unmarshalled
+		// by setting flags which also sets the sources
+		unmarshalFlat func(data []byte, structPtr interface{}, sources *valueSources) error
+	)
 	switch ext {
 	case ".json":
-		return LoadJSON(filename, structPtr)
+		unmarshal = unmarshalJSON
 	case ".xml":
-		return LoadXML(filename, structPtr)
+		unmarshal = unmarshalXML
+	case ".yaml", ".yml":
+		unmarshal = unmarshalYAML
+	case ".toml":
+		unmarshal = unmarshalTOML
+	case ".ini":
+		unmarshalFlat = unmarshalINI
+	case ".env":
+		unmarshalFlat = unmarshalDotEnv
+	case ".properties":
+		unmarshalFlat = unmarshalProperties
+	default:
+		return errors.New("file extension not supported: " + ext)
+	}
+	data, err := ioutil.ReadFile(filename) //#nosec G304
+	if err != nil {
+		return err
 	}
-	return errors.New("file extension not supported: " + ext)
+	if unmarshalFlat != nil {
+		err = unmarshalFlat(data, structPtr, sources)
+		if err != nil {
+			return fmt.Errorf("%s:%w", filename, err)
+		}
+		return checkEnums(structPtr)
+	}
+	err = unmarshal(data, structPtr)
+	if err != nil {
+		return err
+	}
+	if sources != nil && needsValueSources(reflect.TypeOf(structPtr)) {
+		err = setFileSources(data, unmarshal, structPtr, sources)
+		if err != nil {
+			return err
+		}
+	}
+	return checkEnums(structPtr)
 }
 
-// LoadXML loads a struct from a XML file
+// LoadXML loads a struct from a XML file.
+// Elements named like a flag alias of a field are loaded into that field.
 func LoadXML(filename string, structPtr interface{}) error {
 	filename = filepath.Clean(filename)
 	data, err := ioutil.ReadFile(filename) //#nosec G304
 	if err != nil {
 		return err
 	}
+	return unmarshalXML(data, structPtr)
+}
+
+func unmarshalXML(data []byte, structPtr interface{}) (err error) {
+	if hasAliases(reflect.TypeOf(structPtr)) {
+		data, err = renameXMLAliases(data, reflect.TypeOf(structPtr))
+		if err != nil {
+			return err
+		}
+	}
 	return xml.Unmarshal(data, structPtr)
 }
 
@@ -45,13 +110,24 @@ func SaveXML(filename string, structPtr interface{}, indent ...string) error {
 	return ioutil.WriteFile(filename, data, 0600)
 }
 
-// LoadJSON loads a struct from a JSON file
+// LoadJSON loads a struct from a JSON file.
+// Object keys named like a flag alias of a field are loaded into that field.
 func LoadJSON(filename string, structPtr interface{}) error {
 	filename = filepath.Clean(filename)
 	data, err := ioutil.ReadFile(filename) //#nosec G304
 	if err != nil {
 		return err
 	}
+	return unmarshalJSON(data, structPtr)
+}
+
+func unmarshalJSON(data []byte, structPtr interface{}) (err error) {
+	if hasAliases(reflect.TypeOf(structPtr)) {
+		data, err = renameJSONAliases(data, reflect.TypeOf(structPtr))
+		if err != nil {
+			return err
+		}
+	}
 	return json.Unmarshal(data, structPtr)
 }
 
@@ -64,3 +140,72 @@ func SaveJSON(filename string, structPtr interface{}, indent ...string) error {
 	}
 	return ioutil.WriteFile(filename, data, 0600)
 }
+
+// LoadYAML loads a struct from a YAML file.
+// Mapping keys named like the flag name or a flag alias
+// of a field are loaded into that field.
+func LoadYAML(filename string, structPtr interface{}) error {
+	filename = filepath.Clean(filename)
+	data, err := ioutil.ReadFile(filename) //#nosec G304
+	if err != nil {
+		return err
+	}
+	return unmarshalYAML(data, structPtr)
+}
+
+func unmarshalYAML(data []byte, structPtr interface{}) error {
+	var node yaml.Node
+	err := yaml.Unmarshal(data, &node)
+	if err != nil {
+		return err
+	}
+	if node.Kind == 0 {
+		// Empty document
+		return nil
+	}
+	renameYAMLAliases(&node, reflect.TypeOf(structPtr))
+	return node.Decode(structPtr)
+}
+
+// SaveYAML saves a struct as a YAML file
+func SaveYAML(filename string, structPtr interface{}) error {
+	filename = filepath.Clean(filename)
+	data, err := yaml.Marshal(structPtr)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0600)
+}
+
+// LoadTOML loads a struct from a TOML file.
+// Tables are loaded into nested struct fields and
+// arrays of tables into slices of structs.
+// Keys named like the flag name or a flag alias
+// of a field are loaded into that field.
+func LoadTOML(filename string, structPtr interface{}) error {
+	filename = filepath.Clean(filename)
+	data, err := ioutil.ReadFile(filename) //#nosec G304
+	if err != nil {
+		return err
+	}
+	return unmarshalTOML(data, structPtr)
+}
+
+func unmarshalTOML(data []byte, structPtr interface{}) (err error) {
+	data, err = renameTOMLAliases(data, reflect.TypeOf(structPtr))
+	if err != nil {
+		return err
+	}
+	return toml.Unmarshal(data, structPtr)
+}
+
+// SaveTOML saves a struct as a TOML file
+func SaveTOML(filename string, structPtr interface{}) error {
+	filename = filepath.Clean(filename)
+	var buf bytes.Buffer
+	err := toml.NewEncoder(&buf).Encode(structPtr)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0600)
+}
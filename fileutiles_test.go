@@ -0,0 +1,125 @@
+package structflag
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type tomlServer struct {
+	Host string `toml:"host"`
+	Port int    `toml:"port"`
+}
+
+type tomlConfig struct {
+	Title    string        `toml:"title"`
+	Timeout  time.Duration `toml:"timeout"`
+	Owner    tomlServer    `toml:"owner"`
+	Backup   *tomlServer   `toml:"backup"`
+	Servers  []tomlServer  `toml:"servers"`
+	MaxConns int           `flag:"max-conns,connections" toml:"maxConns"`
+}
+
+func TestLoadTOML(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    tomlConfig
+		wantErr bool
+	}{
+		{
+			name: "keys",
+			data: "title = \"x\"\ntimeout = \"1m30s\"\n",
+			want: tomlConfig{Title: "x", Timeout: 90 * time.Second},
+		},
+		{
+			name: "tables",
+			data: "[owner]\nhost = \"a\"\n\n[backup]\nport = 2\n",
+			want: tomlConfig{Owner: tomlServer{Host: "a"}, Backup: &tomlServer{Port: 2}},
+		},
+		{
+			name: "dotted keys and inline tables",
+			data: "owner.host = \"a\"\nbackup = {host = \"b\", port = 3}\n",
+			want: tomlConfig{Owner: tomlServer{Host: "a"}, Backup: &tomlServer{Host: "b", Port: 3}},
+		},
+		{
+			name: "arrays of tables",
+			data: "[[servers]]\nhost = \"a\"\n\n[[servers]]\nhost = \"b\"\nport = 2\n",
+			want: tomlConfig{Servers: []tomlServer{{Host: "a"}, {Host: "b", Port: 2}}},
+		},
+		{
+			name: "flag name and alias keys",
+			data: "connections = 5\n",
+			want: tomlConfig{MaxConns: 5},
+		},
+		{
+			name:    "invalid",
+			data:    "[owner\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong type",
+			data:    "title = 1\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "config.toml")
+			err := os.WriteFile(filename, []byte(tt.data), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got tomlConfig
+			err = LoadTOML(filename, &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("LoadTOML() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadTOML() = %+v, want %+v", got, tt.want)
+			}
+
+			// LoadFile dispatches on the file extension
+			var loaded tomlConfig
+			err = LoadFile(filename, &loaded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(loaded, tt.want) {
+				t.Errorf("LoadFile() = %+v, want %+v", loaded, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveTOML(t *testing.T) {
+	config := tomlConfig{
+		Title:    "x",
+		Timeout:  time.Minute,
+		Owner:    tomlServer{Host: "a", Port: 1},
+		Backup:   &tomlServer{Host: "b"},
+		Servers:  []tomlServer{{Host: "c"}, {Host: "d", Port: 2}},
+		MaxConns: 5,
+	}
+	filename := filepath.Join(t.TempDir(), "config.toml")
+	err := SaveTOML(filename, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded tomlConfig
+	err = LoadTOML(filename, &loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, config) {
+		t.Errorf("LoadTOML() of saved file = %+v, want %+v", loaded, config)
+	}
+}
@@ -0,0 +1,367 @@
+package structflag
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ogier/pflag"
+	reflection "github.com/ungerik/go-reflection"
+)
+
+// flatFileValue is a key value pair loaded from
+// an INI, .env or .properties file
+type flatFileValue struct {
+	line  int
+	key   string
+	value string
+}
+
+// setFlatFileValues sets the fields of structPtr by parsing the values
+// like flags with the keys as flag names or aliases.
+// If flagKey is not nil, then the keys are matched
+// with flagKey applied to the flag names instead.
+// The keys of envNames are matched additionally with the flag names as values.
+// Keys are matched case insensitive and keys that match no flag are ignored.
+// The sources of the set flags are set in sources.
+func setFlatFileValues(structPtr interface{}, values []flatFileValue, flagKey func(name string) string, envNames map[string]string, sources *valueSources) error {
+	err := checkStructPtr(structPtr)
+	if err != nil {
+		return err
+	}
+	fileFlags := NewFlags()
+	errs := structVar(structPtr, "", nil, newFlagRelations(reflect.TypeOf(structPtr)), sources, fileFlags, fieldValueDefaults, nil)
+	if len(errs) > 0 {
+		return errs
+	}
+	setter, ok := fileFlags.(interface {
+		VisitAll(fn func(*pflag.Flag))
+		Set(name, value string) error
+	})
+	if !ok {
+		return fmt.Errorf("flags of type %T can't be set by name", fileFlags)
+	}
+	flagNames := make(map[string]string)
+	secrets := make(map[string]bool)
+	setter.VisitAll(func(flag *pflag.Flag) {
+		secrets[flag.Name] = isSecretValue(flag.Value)
+		if flagKey != nil {
+			flagNames[strings.ToLower(flagKey(flag.Name))] = flag.Name
+		} else {
+			flagNames[strings.ToLower(flag.Name)] = flag.Name
+		}
+	})
+	for key, name := range envNames {
+		flagNames[strings.ToLower(key)] = name
+	}
+	for _, v := range values {
+		name, ok := flagNames[strings.ToLower(v.key)]
+		if !ok {
+			continue
+		}
+		err = setter.Set(name, v.value)
+		if err != nil {
+			value := v.value
+			if secrets[name] {
+				value = SecretMask
+			}
+			return fmt.Errorf("%d: invalid value %q for %s: %w", v.line, value, v.key, err)
+		}
+	}
+	return nil
+}
+
+// loadFlatFile loads an INI, .env or .properties file with unmarshal.
+// If structPtr was passed to StructVar, then the loaded values
+// count as set for RequiredTag, XorTag and RequiresTag in Parse.
+func loadFlatFile(filename string, structPtr interface{}, unmarshal func([]byte, interface{}, *valueSources) error) error {
+	filename = filepath.Clean(filename)
+	data, err := ioutil.ReadFile(filename) //#nosec G304
+	if err != nil {
+		return err
+	}
+	err = unmarshal(data, structPtr, boundValueSources(structPtr))
+	if err != nil {
+		return fmt.Errorf("%s:%w", filename, err)
+	}
+	return nil
+}
+
+// LoadINI loads a struct from an INI file.
+// The keys are the flag names or aliases of the fields
+// and the values are parsed like flags.
+// Sections map to nested structs, so the key
+// host in the section [db] sets the flag db.host
+// if NestedNameSeparator is the default ".".
+// Section and key names are matched case insensitive
+// and keys that match no flag are ignored.
+// Lines starting with ; or # are comments.
+func LoadINI(filename string, structPtr interface{}) error {
+	return loadFlatFile(filename, structPtr, unmarshalINI)
+}
+
+func unmarshalINI(data []byte, structPtr interface{}, sources *valueSources) error {
+	values, err := parseINI(data)
+	if err != nil {
+		return err
+	}
+	return setFlatFileValues(structPtr, values, nil, nil, sources)
+}
+
+func parseINI(data []byte) (values []flatFileValue, err error) {
+	var (
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+		section string
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || text[0] == ';' || text[0] == '#':
+			continue
+		case text[0] == '[':
+			if !strings.HasSuffix(text, "]") {
+				return nil, fmt.Errorf("%d: invalid section %s", line, text)
+			}
+			section = strings.TrimSpace(text[1 : len(text)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("%d: missing = in %s", line, text)
+		}
+		key = strings.TrimSpace(key)
+		if section != "" {
+			key = section + NestedNameSeparator + key
+		}
+		value, err = unquoteValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", line, err)
+		}
+		values = append(values, flatFileValue{line: line, key: key, value: value})
+	}
+	return values, scanner.Err()
+}
+
+// LoadDotEnv loads a struct from a .env file with KEY=VALUE lines.
+// The keys are the environment variable names of the fields
+// defined by EnvTag or EnvPrefix or the flag names and aliases
+// in upper case with underscores, like DB_HOST for the flag db.host.
+// Keys are matched case insensitive and keys that match no flag are ignored.
+// The values are parsed like flags. Values can be quoted
+// with double quotes supporting escape sequences or
+// with single quotes for literal values.
+// An optional export prefix of lines is ignored
+// and lines starting with # are comments.
+func LoadDotEnv(filename string, structPtr interface{}) error {
+	return loadFlatFile(filename, structPtr, unmarshalDotEnv)
+}
+
+func unmarshalDotEnv(data []byte, structPtr interface{}, sources *valueSources) error {
+	values, err := parseDotEnv(data)
+	if err != nil {
+		return err
+	}
+	envNames := make(map[string]string)
+	visitFlagFields(reflection.DerefType(reflect.TypeOf(structPtr)), "", func(field reflect.StructField, name string) {
+		if env := fieldEnvName(field, name); env != "" {
+			envNames[env] = name
+		}
+	})
+	return setFlatFileValues(structPtr, values, envVarName, envNames, sources)
+}
+
+func parseDotEnv(data []byte) (values []flatFileValue, err error) {
+	var (
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		if rest := strings.TrimPrefix(text, "export"); len(rest) < len(text) && strings.HasPrefix(rest, " ") {
+			text = strings.TrimSpace(rest)
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("%d: missing = in %s", line, text)
+		}
+		value = strings.TrimSpace(value)
+		if value != "" && value[0] != '"' && value[0] != '\'' {
+			// Remove inline comment from unquoted value
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		value, err = unquoteValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", line, err)
+		}
+		values = append(values, flatFileValue{line: line, key: strings.TrimSpace(key), value: value})
+	}
+	return values, scanner.Err()
+}
+
+// unquoteValue removes double quotes interpreting escape sequences
+// or single quotes without interpreting escape sequences from value.
+// Text following the closing quote is ignored if it is a comment.
+func unquoteValue(value string) (string, error) {
+	if value == "" || (value[0] != '"' && value[0] != '\'') {
+		return value, nil
+	}
+	quote := value[0]
+	end := 1
+	for ; end < len(value); end++ {
+		if value[end] == '\\' && quote == '"' {
+			end++
+			continue
+		}
+		if value[end] == quote {
+			break
+		}
+	}
+	if end >= len(value) {
+		return "", fmt.Errorf("missing closing quote in %s", value)
+	}
+	if rest := strings.TrimSpace(value[end+1:]); rest != "" && rest[0] != '#' && rest[0] != ';' {
+		return "", fmt.Errorf("unexpected %s after closing quote", rest)
+	}
+	if quote == '\'' {
+		return value[1:end], nil
+	}
+	return strconv.Unquote(value[:end+1])
+}
+
+// LoadProperties loads a struct from a Java .properties file.
+// The keys are the flag names or aliases of the fields
+// and the values are parsed like flags.
+// Keys are matched case insensitive and keys that match no flag are ignored.
+// Keys and values are separated by =, : or whitespace.
+// Lines ending with a backslash are continued on the next line,
+// escape sequences like \t, \n and \uXXXX are supported
+// and lines starting with # or ! are comments.
+func LoadProperties(filename string, structPtr interface{}) error {
+	return loadFlatFile(filename, structPtr, unmarshalProperties)
+}
+
+func unmarshalProperties(data []byte, structPtr interface{}, sources *valueSources) error {
+	values, err := parseProperties(data)
+	if err != nil {
+		return err
+	}
+	return setFlatFileValues(structPtr, values, nil, nil, sources)
+}
+
+func parseProperties(data []byte) (values []flatFileValue, err error) {
+	var (
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		start := line
+		text := strings.TrimLeft(scanner.Text(), " \t\f")
+		if text == "" || text[0] == '#' || text[0] == '!' {
+			continue
+		}
+		// Join continued lines
+		for endsWithEscape(text) && scanner.Scan() {
+			line++
+			text = text[:len(text)-1] + strings.TrimLeft(scanner.Text(), " \t\f")
+		}
+		key, value := splitProperty(text)
+		key, err = unescapeProperty(key)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", start, err)
+		}
+		value, err = unescapeProperty(value)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", start, err)
+		}
+		values = append(values, flatFileValue{line: start, key: key, value: value})
+	}
+	return values, scanner.Err()
+}
+
+// endsWithEscape returns if text ends with an odd number of backslashes
+func endsWithEscape(text string) bool {
+	n := 0
+	for i := len(text) - 1; i >= 0 && text[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits text at the first unescaped =, : or whitespace
+// into the still escaped key and value of a property.
+func splitProperty(text string) (key, value string) {
+	end := 0
+	for ; end < len(text); end++ {
+		c := text[end]
+		if c == '\\' {
+			end++
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+	}
+	if end >= len(text) {
+		return text, ""
+	}
+	key = text[:end]
+	value = strings.TrimLeft(text[end:], " \t\f")
+	if value != "" && (value[0] == '=' || value[0] == ':') {
+		value = strings.TrimLeft(value[1:], " \t\f")
+	}
+	return key, value
+}
+
+func unescapeProperty(str string) (string, error) {
+	if !strings.Contains(str, `\`) {
+		return str, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if c != '\\' || i+1 == len(str) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = str[i]; c {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(str) {
+				return "", fmt.Errorf("invalid unicode escape in %s", str)
+			}
+			r, err := strconv.ParseUint(str[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape in %s", str)
+			}
+			var buf [utf8.UTFMax]byte
+			b.Write(buf[:utf8.EncodeRune(buf[:], rune(r))])
+			i += 4
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,229 @@
+package structflag
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseINI(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []flatFileValue
+		wantErr bool
+	}{
+		{
+			name: "global keys",
+			data: "name = test\nport=80\n",
+			want: []flatFileValue{{1, "name", "test"}, {2, "port", "80"}},
+		},
+		{
+			name: "comments and empty lines",
+			data: "; comment\n\n# comment\nname = test\n",
+			want: []flatFileValue{{4, "name", "test"}},
+		},
+		{
+			name: "sections",
+			data: "name=a\n[db]\nhost = h\n[db.replica]\nhost = r\n",
+			want: []flatFileValue{{1, "name", "a"}, {3, "db.host", "h"}, {5, "db.replica.host", "r"}},
+		},
+		{
+			name: "quoted values",
+			data: "a = \"x y\"\nb = 'x\\ty'\nc = \"x\\ty\" ; comment\nd = \"a=b\"\n",
+			want: []flatFileValue{{1, "a", "x y"}, {2, "b", `x\ty`}, {3, "c", "x\ty"}, {4, "d", "a=b"}},
+		},
+		{
+			name: "empty value",
+			data: "a =\n",
+			want: []flatFileValue{{1, "a", ""}},
+		},
+		{name: "missing equal sign", data: "name\n", wantErr: true},
+		{name: "unclosed section", data: "[db\n", wantErr: true},
+		{name: "unclosed quote", data: "a = \"x\n", wantErr: true},
+		{name: "text after quote", data: "a = \"x\" y\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseINI([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseINI() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseINI() error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseINI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDotEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []flatFileValue
+		wantErr bool
+	}{
+		{
+			name: "plain values",
+			data: "NAME=test\nPORT = 80\n",
+			want: []flatFileValue{{1, "NAME", "test"}, {2, "PORT", "80"}},
+		},
+		{
+			name: "export prefix",
+			data: "export NAME=test\nexport  PORT=80\nexported=x\n",
+			want: []flatFileValue{{1, "NAME", "test"}, {2, "PORT", "80"}, {3, "exported", "x"}},
+		},
+		{
+			name: "comments",
+			data: "# comment\n\nNAME=test # comment\nURL=http://host/#anchor\n",
+			want: []flatFileValue{{3, "NAME", "test"}, {4, "URL", "http://host/#anchor"}},
+		},
+		{
+			name: "double quotes",
+			data: "A=\"x y\"\nB=\"line\\nbreak\"\nC=\"say \\\"hi\\\"\" # comment\nD=\"#x\"\n",
+			want: []flatFileValue{{1, "A", "x y"}, {2, "B", "line\nbreak"}, {3, "C", `say "hi"`}, {4, "D", "#x"}},
+		},
+		{
+			name: "single quotes",
+			data: "A='x\\ny'\nB='\"x\"'\n",
+			want: []flatFileValue{{1, "A", `x\ny`}, {2, "B", `"x"`}},
+		},
+		{
+			name: "equal sign in value",
+			data: "A=b=c\n",
+			want: []flatFileValue{{1, "A", "b=c"}},
+		},
+		{name: "missing equal sign", data: "NAME\n", wantErr: true},
+		{name: "unclosed double quote", data: "A=\"x\n", wantErr: true},
+		{name: "unclosed single quote", data: "A='x\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDotEnv([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseDotEnv() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDotEnv() error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDotEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []flatFileValue
+		wantErr bool
+	}{
+		{
+			name: "separators",
+			data: "a=1\nb = 2\nc:3\nd : 4\ne 5\nf\t6\n",
+			want: []flatFileValue{{1, "a", "1"}, {2, "b", "2"}, {3, "c", "3"}, {4, "d", "4"}, {5, "e", "5"}, {6, "f", "6"}},
+		},
+		{
+			name: "comments",
+			data: "# comment\n! comment\n  # indented\n\na=1\n",
+			want: []flatFileValue{{5, "a", "1"}},
+		},
+		{
+			name: "key without value",
+			data: "a\nb=\n",
+			want: []flatFileValue{{1, "a", ""}, {2, "b", ""}},
+		},
+		{
+			name: "line continuation",
+			data: "a = one, \\\n    two, \\\n    three\nb=2\n",
+			want: []flatFileValue{{1, "a", "one, two, three"}, {4, "b", "2"}},
+		},
+		{
+			name: "escaped backslash at line end",
+			data: "a = x\\\\\nb=2\n",
+			want: []flatFileValue{{1, "a", `x\`}, {2, "b", "2"}},
+		},
+		{
+			name: "escapes",
+			data: "a = tab\\tnew\\nline\nb = \\u00e4\\u20AC\nc = \\x\n",
+			want: []flatFileValue{{1, "a", "tab\tnew\nline"}, {2, "b", "ä€"}, {3, "c", "x"}},
+		},
+		{
+			name: "escaped separators in key",
+			data: "a\\=b\\:c\\ d = 1\n",
+			want: []flatFileValue{{1, "a=b:c d", "1"}},
+		},
+		{
+			name: "separator in value",
+			data: "url = http://host:80/?a=b\n",
+			want: []flatFileValue{{1, "url", "http://host:80/?a=b"}},
+		},
+		{name: "short unicode escape", data: "a = \\u12\n", wantErr: true},
+		{name: "invalid unicode escape", data: "a = \\uXYZW\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProperties([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseProperties() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseProperties() error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProperties() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type flatFileDatabaseConfig struct {
+	Host string
+	Port uint16
+}
+
+type flatFileConfig struct {
+	Name string
+	DB   flatFileDatabaseConfig
+}
+
+func TestLoadINIMatchesCaseInsensitive(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.ini")
+	err := os.WriteFile(filename, []byte("name = test\n[db]\nhost = a\nport = 70\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config flatFileConfig
+	err = LoadFile(filename, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := flatFileConfig{Name: "test", DB: flatFileDatabaseConfig{Host: "a", Port: 70}}
+	if config != want {
+		t.Errorf("LoadFile() = %+v, want %+v", config, want)
+	}
+
+	err = os.WriteFile(filename, []byte("[db]\nport = 70000\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = LoadFile(filename, &config)
+	if err == nil {
+		t.Error("LoadFile() with out of range port, want error")
+	}
+}
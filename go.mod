@@ -3,9 +3,11 @@ module github.com/ungerik/go-structflag
 go 1.19
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/fatih/color v1.14.1
 	github.com/ogier/pflag v0.0.1
 	github.com/ungerik/go-reflection v0.0.0-20220113085621-6c5fc1f2694a
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
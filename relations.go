@@ -0,0 +1,86 @@
+package structflag
+
+import (
+	"reflect"
+	"strings"
+
+	reflection "github.com/ungerik/go-reflection"
+)
+
+// flagRelations holds the relationships between
+// flags declared with XorTag and RequiresTag.
+type flagRelations struct {
+	// xorGroups maps XorTag group names to flag names
+	xorGroups map[string][]string
+	// related contains the names of all flags
+	// that are part of a relationship
+	related map[string]bool
+}
+
+// newFlagRelations returns the flagRelations
+// of the fields of the struct type t.
+func newFlagRelations(t reflect.Type) *flagRelations {
+	r := &flagRelations{
+		xorGroups: make(map[string][]string),
+		related:   make(map[string]bool),
+	}
+	r.collect(reflection.DerefType(t), "")
+	return r
+}
+
+func (r *flagRelations) collect(t reflect.Type, namePrefix string) {
+	for _, f := range reflection.FlatExportedNamedStructFields(t, "") {
+		fieldName, ok := fieldFlagName(f.Field)
+		if !ok {
+			continue
+		}
+		fieldType := reflection.DerefType(f.Field.Type)
+		if isNestedStructType(fieldType) {
+			r.collect(fieldType, namePrefix+nestedNamePrefix(f.Field, fieldName))
+			continue
+		}
+		name := NameFunc(namePrefix + fieldName)
+		for _, group := range tagList(f.Field, XorTag) {
+			r.xorGroups[group] = append(r.xorGroups[group], name)
+			r.related[name] = true
+		}
+		for _, required := range tagList(f.Field, RequiresTag) {
+			r.related[name] = true
+			r.related[required] = true
+		}
+	}
+}
+
+// usage returns the usage description of the relationships
+// of the flag name with the given field.
+func (r *flagRelations) usage(field reflect.StructField, name string) string {
+	var usage []string
+	for _, group := range tagList(field, XorTag) {
+		var others []string
+		for _, other := range r.xorGroups[group] {
+			if other != name {
+				others = append(others, "--"+other)
+			}
+		}
+		if len(others) > 0 {
+			usage = append(usage, "(mutually exclusive with "+strings.Join(others, ", ")+")")
+		}
+	}
+	if required := tagList(field, RequiresTag); len(required) > 0 {
+		usage = append(usage, "(requires --"+strings.Join(required, ", --")+")")
+	}
+	return strings.Join(usage, " ")
+}
+
+// tagList returns the trimmed comma separated values of a struct tag
+func tagList(field reflect.StructField, tag string) []string {
+	value := field.Tag.Get(tag)
+	if value == "" {
+		return nil
+	}
+	list := strings.Split(value, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
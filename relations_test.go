@@ -0,0 +1,119 @@
+package structflag
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type relationsConfig struct {
+	JSON bool   `flag:"json" json:"json" xor:"format"`
+	YAML bool   `flag:"yaml" json:"yaml" xor:"format"`
+	User string `flag:"user" json:"user" requires:"pass"`
+	Pass string `flag:"pass" json:"pass" secret:"true"`
+}
+
+func TestFlagRelations(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		args     []string
+		wantErrs []string
+	}{
+		{
+			name: "nothing set",
+		},
+		{
+			name: "one of group",
+			args: []string{"--json"},
+		},
+		{
+			name:     "group conflict in flags",
+			args:     []string{"--json", "--yaml"},
+			wantErrs: []string{"yaml " + XorTag},
+		},
+		{
+			name:     "group conflict between file and flag",
+			data:     `{"yaml": false}`,
+			args:     []string{"--json"},
+			wantErrs: []string{"yaml " + XorTag},
+		},
+		{
+			name: "requires from flags",
+			args: []string{"--user=u", "--pass=p"},
+		},
+		{
+			name: "requires from file",
+			data: `{"pass": "p"}`,
+			args: []string{"--user=u"},
+		},
+		{
+			name:     "requires missing",
+			args:     []string{"--user=u"},
+			wantErrs: []string{"user " + RequiresTag},
+		},
+		{
+			name: "required flag alone",
+			args: []string{"--pass=p"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			filename := filepath.Join(t.TempDir(), "config.json")
+			if tt.data != "" {
+				err := os.WriteFile(filename, []byte(tt.data), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, _, err := Bind[relationsConfig](WithOptionalFile(filename), WithArgs(tt.args...))
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("Bind() error: %s", err)
+				}
+				return
+			}
+			var errs FieldErrors
+			if !errors.As(err, &errs) || len(errs) != len(tt.wantErrs) {
+				t.Fatalf("Bind() = %v, want errors %v", err, tt.wantErrs)
+			}
+			for i, want := range tt.wantErrs {
+				if got := errs[i].Flag + " " + errs[i].Tag; got != want {
+					t.Errorf("error %d = %s, want %s", i, errs[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestFlagRelationsUsage(t *testing.T) {
+	resetFlags(t)
+	config := struct {
+		A int `xor:"g1"`
+		B int `xor:"g1,g2"`
+		C int `xor:"g2" requires:"A, D"`
+		D int
+		E int `xor:"alone"`
+	}{}
+	err := StructVarE(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		flag string
+		want string
+	}{
+		{"A", "(mutually exclusive with --B)"},
+		{"B", "(mutually exclusive with --A) (mutually exclusive with --C)"},
+		{"C", "(mutually exclusive with --B) (requires --A, --D)"},
+		{"D", ""},
+		{"E", ""},
+	}
+	for _, tt := range tests {
+		if got := lookupFlag(t, tt.flag).Usage; got != tt.want {
+			t.Errorf("usage of --%s = %q, want %q", tt.flag, got, tt.want)
+		}
+	}
+}
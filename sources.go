@@ -0,0 +1,187 @@
+package structflag
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+
+	"github.com/ogier/pflag"
+	reflection "github.com/ungerik/go-reflection"
+)
+
+// valueSource is the source that set the value of a flag
+type valueSource int
+
+const (
+	noSource valueSource = iota
+	fileSource
+	flagSource
+)
+
+// valueSources holds the sources of the flag values of one
+// struct defined as flags by flag name, together with errors
+// for flags that were set by conflicting negatable flags,
+// like --foo and --no-foo.
+// The methods of a nil *valueSources don't track anything.
+type valueSources struct {
+	sources   map[string]valueSource
+	conflicts map[string]error
+}
+
+func newValueSources() *valueSources {
+	return &valueSources{
+		sources:   make(map[string]valueSource),
+		conflicts: make(map[string]error),
+	}
+}
+
+func (s *valueSources) set(name string, source valueSource) {
+	if s != nil {
+		s.sources[name] = source
+	}
+}
+
+func (s *valueSources) get(name string) valueSource {
+	if s == nil {
+		return noSource
+	}
+	return s.sources[name]
+}
+
+func (s *valueSources) setConflict(name string, err error) {
+	if s != nil {
+		s.conflicts[name] = err
+	}
+}
+
+func (s *valueSources) conflict(name string) error {
+	if s == nil {
+		return nil
+	}
+	return s.conflicts[name]
+}
+
+// sourceValue wraps the pflag.Value of a flag
+// and sets flagSource as source of the flag
+// after the value has been set.
+type sourceValue struct {
+	pflag.Value
+	sources *valueSources
+	name    string
+}
+
+func (s *sourceValue) Set(str string) error {
+	err := s.Value.Set(str)
+	if err != nil {
+		return err
+	}
+	s.sources.set(s.name, flagSource)
+	return nil
+}
+
+func (s *sourceValue) IsBoolFlag() bool {
+	return isBoolFlag(s.Value)
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	xmlUnmarshalerType  = reflect.TypeOf((*xml.Unmarshaler)(nil)).Elem()
+	xmlNameType         = reflect.TypeOf(xml.Name{})
+)
+
+// setFileSources unmarshals data a second time into a shadow struct
+// of structPtr with pointer fields to find out which fields
+// have values in the file and sets fileSource for their flags.
+func setFileSources(data []byte, unmarshal func([]byte, interface{}) error, structPtr interface{}, sources *valueSources) error {
+	t := reflect.TypeOf(structPtr).Elem()
+	shadow := reflect.New(shadowStructType(t))
+	err := unmarshal(data, shadow.Interface())
+	if err != nil {
+		return err
+	}
+	setShadowSources(t, shadow.Elem(), "", sources)
+	return nil
+}
+
+// shadowStructType returns a struct type with the flattened exported
+// fields of the struct type t, where every field is a pointer
+// so that a nil pointer means the field was not loaded.
+func shadowStructType(t reflect.Type) reflect.Type {
+	var fields []reflect.StructField
+	names := make(map[string]bool)
+	for _, f := range reflection.FlatExportedNamedStructFields(t, "") {
+		field := f.Field
+		if names[field.Name] {
+			continue
+		}
+		names[field.Name] = true
+		switch {
+		case field.Type == xmlNameType:
+			// Keep the type so that encoding/xml recognizes the field
+		case isShadowNestedType(field.Type):
+			field.Type = reflect.PtrTo(shadowStructType(field.Type))
+		case isShadowNestedType(reflection.DerefType(field.Type)):
+			field.Type = reflect.PtrTo(shadowStructType(field.Type.Elem()))
+		case field.Type.Kind() != reflect.Ptr:
+			field.Type = reflect.PtrTo(field.Type)
+		}
+		field.Index = nil
+		field.Offset = 0
+		field.Anonymous = false
+		fields = append(fields, field)
+	}
+	return reflect.StructOf(fields)
+}
+
+func isShadowNestedType(t reflect.Type) bool {
+	return isNestedStructType(t) &&
+		!reflect.PtrTo(t).Implements(jsonUnmarshalerType) &&
+		!reflect.PtrTo(t).Implements(xmlUnmarshalerType)
+}
+
+// setShadowSources sets fileSource for the flags of all fields
+// of the struct type t that have a non nil value in shadow.
+func setShadowSources(t reflect.Type, shadow reflect.Value, namePrefix string, sources *valueSources) {
+	for _, f := range reflection.FlatExportedNamedStructFields(t, "") {
+		fieldName, ok := fieldFlagName(f.Field)
+		if !ok {
+			continue
+		}
+		s := shadow.FieldByName(f.Field.Name)
+		if !s.IsValid() || s.Kind() != reflect.Ptr || s.IsNil() {
+			continue
+		}
+		ft := reflection.DerefType(f.Field.Type)
+		if isNestedStructType(ft) {
+			prefix := namePrefix + nestedNamePrefix(f.Field, fieldName)
+			if isShadowNestedType(ft) {
+				setShadowSources(ft, s.Elem(), prefix, sources)
+				continue
+			}
+			// Unmarshalled as a whole, so all nested flags have been loaded
+			visitFlagFields(ft, prefix, func(_ reflect.StructField, name string) {
+				sources.set(name, fileSource)
+			})
+			continue
+		}
+		sources.set(NameFunc(namePrefix+fieldName), fileSource)
+	}
+}
+
+// needsValueSources returns if t or one of its nested struct types
+// has a field with a RequiredTag, XorTag or RequiresTag
+// that needs the sources of the flag values to be checked.
+func needsValueSources(t reflect.Type) bool {
+	for _, f := range reflection.FlatExportedNamedStructFields(reflection.DerefType(t), "") {
+		for _, tag := range []string{RequiredTag, XorTag, RequiresTag} {
+			if _, ok := f.Field.Tag.Lookup(tag); ok {
+				return true
+			}
+		}
+		ft := reflection.DerefType(f.Field.Type)
+		if isNestedStructType(ft) && needsValueSources(ft) {
+			return true
+		}
+	}
+	return false
+}
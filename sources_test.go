@@ -0,0 +1,177 @@
+package structflag
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type sourcesDatabaseConfig struct {
+	Host string `json:"host" yaml:"host" required:"true"`
+}
+
+type sourcesConfig struct {
+	Name string                `json:"name" yaml:"name" required:"true"`
+	Port int                   `json:"port" yaml:"port" default:"80"`
+	DB   sourcesDatabaseConfig `json:"db" yaml:"db"`
+}
+
+func TestRequiredValueSources(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		data        string
+		args        []string
+		wantMissing []string
+		wantLoadErr bool
+	}{
+		{
+			name: "all from file",
+			file: "config.json",
+			data: `{"name": "x", "db": {"host": "h"}}`,
+		},
+		{
+			name: "file and flag",
+			file: "config.json",
+			data: `{"db": {"host": "h"}}`,
+			args: []string{"--Name=x"},
+		},
+		{
+			name: "empty values from file",
+			file: "config.yaml",
+			data: "name: \"\"\ndb:\n  host: \"\"\n",
+		},
+		{
+			name:        "nested missing",
+			file:        "config.json",
+			data:        `{"name": "x", "port": 8080}`,
+			wantMissing: []string{"DB.Host"},
+		},
+		{
+			name:        "no file",
+			args:        []string{"--DB.Host=h"},
+			wantMissing: []string{"Name"},
+		},
+		{
+			name:        "invalid file",
+			file:        "config.json",
+			data:        `{"name": "x",}`,
+			wantLoadErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			filename := filepath.Join(t.TempDir(), "config.json")
+			if tt.file != "" {
+				filename = filepath.Join(t.TempDir(), tt.file)
+				err := os.WriteFile(filename, []byte(tt.data), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, _, err := Bind[sourcesConfig](WithOptionalFile(filename), WithArgs(tt.args...))
+			var errs FieldErrors
+			isFieldErrors := errors.As(err, &errs)
+			switch {
+			case tt.wantLoadErr:
+				if err == nil || isFieldErrors {
+					t.Errorf("Bind() = %v, want load error", err)
+				}
+			case len(tt.wantMissing) == 0:
+				if err != nil {
+					t.Errorf("Bind() error: %s", err)
+				}
+			default:
+				if !isFieldErrors || len(errs) != len(tt.wantMissing) {
+					t.Fatalf("Bind() = %v, want missing %v", err, tt.wantMissing)
+				}
+				for i, name := range tt.wantMissing {
+					if errs[i].Flag != name || errs[i].Tag != RequiredTag {
+						t.Errorf("error %d = %s, want missing --%s", i, errs[i], name)
+					}
+				}
+			}
+		})
+	}
+}
+
+type shadowDatabase struct {
+	Host string `flag:"host" json:"host" xml:"host" yaml:"host" toml:"host"`
+	Port int    `flag:"port" json:"port" xml:"port" yaml:"port" toml:"port"`
+}
+
+type shadowEmbedded struct {
+	Debug bool `flag:"debug" json:"debug" xml:"debug" yaml:"debug" toml:"debug"`
+}
+
+type shadowConfig struct {
+	shadowEmbedded `yaml:",inline"`
+	Name           string          `flag:"name" json:"name" xml:"name" yaml:"name" toml:"name"`
+	Size           ByteSize        `flag:"size" json:"size" xml:"size" yaml:"size" toml:"size"`
+	DB             shadowDatabase  `flag:"db" json:"db" xml:"db" yaml:"db" toml:"db"`
+	Opt            *shadowDatabase `flag:"opt" json:"opt" xml:"opt" yaml:"opt" toml:"opt"`
+	Ignored        string          `flag:"-" json:"ignored" xml:"ignored" yaml:"ignored" toml:"ignored"`
+}
+
+func TestSetFileSources(t *testing.T) {
+	tests := []struct {
+		name      string
+		unmarshal func([]byte, interface{}) error
+		data      string
+		want      []string
+	}{
+		{
+			name:      "JSON",
+			unmarshal: unmarshalJSON,
+			data:      `{"debug": false, "db": {"port": 1}, "opt": {"host": ""}, "ignored": "x"}`,
+			want:      []string{"debug", "db.port", "opt.host"},
+		},
+		{
+			name:      "XML",
+			unmarshal: unmarshalXML,
+			data:      `<config><name>x</name><db><host>h</host></db><size>1KiB</size></config>`,
+			want:      []string{"name", "size", "db.host"},
+		},
+		{
+			name:      "YAML",
+			unmarshal: unmarshalYAML,
+			data:      "debug: true\nname: \"\"\nopt:\n  port: 2\n",
+			want:      []string{"debug", "name", "opt.port"},
+		},
+		{
+			name:      "TOML",
+			unmarshal: unmarshalTOML,
+			data:      "size = \"1KiB\"\n[db]\nhost = \"h\"\nport = 0\n",
+			want:      []string{"size", "db.host", "db.port"},
+		},
+		{
+			name:      "empty",
+			unmarshal: unmarshalJSON,
+			data:      `{}`,
+		},
+	}
+	allNames := []string{"debug", "name", "size", "db.host", "db.port", "opt.host", "opt.port"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := newValueSources()
+			err := setFileSources([]byte(tt.data), tt.unmarshal, &shadowConfig{}, sources)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := make(map[string]bool)
+			for _, name := range tt.want {
+				want[name] = true
+			}
+			for _, name := range allNames {
+				if got := sources.get(name) == fileSource; got != want[name] {
+					t.Errorf("source of %s is file = %t, want %t", name, got, want[name])
+				}
+			}
+			if len(sources.sources) != len(tt.want) {
+				t.Errorf("sources = %v, want %v", sources.sources, tt.want)
+			}
+		})
+	}
+}
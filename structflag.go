@@ -1,6 +1,8 @@
 package structflag
 
 import (
+	"encoding"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -99,11 +101,35 @@ var (
 	}
 
 	flags Flags
+
+	// boundStructs are the structs passed to StructVar
+	// that are validated by Parse
+	boundStructs []boundStruct
 )
 
+// boundStruct is a struct defined as flags
+// with the sources of its flag values
+type boundStruct struct {
+	structPtr interface{}
+	sources   *valueSources
+}
+
+// boundValueSources returns the sources of the flag values
+// of structPtr if it was passed to StructVar or else nil.
+func boundValueSources(structPtr interface{}) *valueSources {
+	for _, b := range boundStructs {
+		if b.structPtr == structPtr {
+			return b.sources
+		}
+	}
+	return nil
+}
+
 var (
 	// NameTag is the struct tag used to overwrite
 	// the struct field name as flag name.
+	// Further comma separated names are aliases for the flag
+	// that are also accepted as keys in configuration files.
 	// Struct fields with NameTag of "-" will be ignored.
 	NameTag = "flag"
 
@@ -117,16 +143,171 @@ var (
 
 	// DefaultTag is the struct tag used to
 	// define the default value for the field
-	// (if that default value is different from the zero value)
+	// (if that default value is different from the zero value).
+	// Fields implementing pflag.Value get the default with Set.
+	// See StructVar for when it is applied.
 	DefaultTag = "default"
 
+	// HiddenTag is the struct tag used to hide a flag
+	// in the usage output with the value "true".
+	// The flag is still parsed.
+	HiddenTag = "hidden"
+
+	// DeprecatedTag is the struct tag used to deprecate a flag.
+	// The flag is still parsed, but hidden in the usage output
+	// and setting it prints a warning with the tag value to Output.
+	DeprecatedTag = "deprecated"
+
+	// SecretTag is the struct tag used to mark a field as secret
+	// with the value "true". The value of secret fields is shown
+	// as SecretMask in the usage output and by PrintConfig.
+	SecretTag = "secret"
+
+	// SecretMask is shown instead of the value of secret fields
+	SecretMask = "******"
+
+	// CountTag is the struct tag used to define with the value "true"
+	// that an integer field counts how often its flag is set,
+	// like -v -v -v or -vvv for verbosity levels.
+	CountTag = "count"
+
+	// NegatableTag is the struct tag used to define if a bool field
+	// also gets a negated flag with NegationPrefix, like --no-foo.
+	// If not set, bool fields with a default value of true are negatable.
+	NegatableTag = "negatable"
+
+	// NegationPrefix is prepended to the flag name
+	// of negatable bool fields for the negated flag.
+	NegationPrefix = "no-"
+
+	// EnumTag is the struct tag used to define the comma
+	// separated list of allowed values for string and integer fields.
+	// Other values are rejected when parsing flags and by LoadFile.
+	EnumTag = "enum"
+
+	// EnumIgnoreCase defines if string values of
+	// fields with an EnumTag are matched case insensitive.
+	EnumIgnoreCase = false
+
+	// RequiredTag is the struct tag used to mark a field as required
+	// with the value "true". Parse and LoadFileAndParseCommandLine
+	// return an error if the value of a required field was not set.
+	// A value counts as set if it was set by a command line flag,
+	// loaded from a configuration file or set from an environment
+	// variable, even if the value equals the default.
+	// A DefaultTag or a value the field had before does not count.
+	// The same rule applies to XorTag and RequiresTag.
+	RequiredTag = "required"
+
+	// XorTag is the struct tag used to put a field into one or more
+	// comma separated groups of mutually exclusive flags.
+	// Parse and LoadFileAndParseCommandLine return an error
+	// if more than one flag of a group was set,
+	// for example one in a configuration file
+	// and another one on the command line.
+	XorTag = "xor"
+
+	// RequiresTag is the struct tag used to define a comma separated
+	// list of flag names that must be set if the flag of the field is set.
+	// Values from configuration files and environment variables
+	// count as set like for RequiredTag.
+	RequiresTag = "requires"
+
+	// MinTag is the struct tag used to define the minimum value
+	// of a numeric field or the minimum length of a string,
+	// slice or map field, checked by Validate.
+	MinTag = "min"
+
+	// MaxTag is the struct tag used to define the maximum value
+	// of a numeric field or the maximum length of a string,
+	// slice or map field, checked by Validate.
+	MaxTag = "max"
+
+	// LenTag is the struct tag used to define the exact length
+	// of a string, slice or map field, checked by Validate.
+	LenTag = "len"
+
+	// PatternTag is the struct tag used to define a regular
+	// expression that a string field must match, checked by Validate.
+	PatternTag = "pattern"
+
+	// OneOfTag is the struct tag used to define a comma separated
+	// list of allowed values, checked by Validate.
+	// In contrast to EnumTag the values are not
+	// checked while parsing and not listed in the usage.
+	OneOfTag = "oneof"
+
+	// PrefixTag is the struct tag used to overwrite the flag name
+	// prefix of the fields of a named struct field.
+	// Per default the flag name of the struct field is used as prefix.
+	// An empty PrefixTag defines the fields of the nested
+	// struct without a prefix.
+	PrefixTag = "prefix"
+
+	// NestedNameSeparator separates the prefix
+	// of nested struct fields from their flag names.
+	NestedNameSeparator = "."
+
+	// LayoutTag is the struct tag used to define the
+	// layout for parsing and formatting time.Time fields.
+	// Defaults to TimeLayout if not set.
+	LayoutTag = "layout"
+
+	// LocationTag is the struct tag used to define the
+	// time zone location name for parsing time.Time fields,
+	// like "UTC", "Local" or "Europe/Vienna".
+	// Defaults to TimeLocation if not set.
+	LocationTag = "location"
+
+	// EnvTag is the struct tag used to define the name of an
+	// environment variable that sets the value of the field
+	// in LoadFileAndParseCommandLine, Bind and LoadEnv.
+	// The value "-" disables the environment variable of the field.
+	EnvTag = "env"
+
+	// EnvPrefix enables environment variables for all fields
+	// without an EnvTag if not empty. The variable name is
+	// EnvPrefix and an underscore followed by the flag name in
+	// upper case with all other characters than letters and digits
+	// replaced by underscores, like MYAPP_DB_HOST for --db.host.
+	EnvPrefix = ""
+
+	// TimeLayout is the default layout for time.Time fields
+	TimeLayout = time.RFC3339
+
+	// TimeLocation is the default location for time.Time fields
+	TimeLocation = time.Local
+
+	// AllowNilPointers defines if nil pointer struct fields are allowed.
+	// If false, StructVar panics for nil pointer fields.
+	// If true, nil pointer fields stay nil and are only allocated when
+	// their flag or the flag of a field of the pointed to struct is set,
+	// or when the field has a DefaultTag.
+	// Allocated structs get the DefaultTag values of their fields.
+	AllowNilPointers = false
+
+	// Strict defines if StructVarE returns a FieldError for every
+	// exported struct field with a type that can't be defined as flag.
+	// If false, such fields are ignored.
+	// Fields with NameTag of "-" are always ignored.
+	Strict = false
+
 	// NameFunc is called as last operation for every flag name
 	NameFunc = func(name string) string { return name }
+
+	// AppendSliceFlags defines if command line values of slice and map flags
+	// are appended to the existing slice or map, for example one loaded
+	// by LoadFileAndParseCommandLine from a configuration file.
+	// If false, the first command line value of a slice or map flag
+	// replaces the existing slice or map and following values are appended.
+	AppendSliceFlags = false
 )
 
 var (
-	pflagValueType   = reflect.TypeOf((*pflag.Value)(nil)).Elem()
-	timeDurationType = reflect.TypeOf(time.Duration(0))
+	pflagValueType      = reflect.TypeOf((*pflag.Value)(nil)).Elem()
+	timeDurationType    = reflect.TypeOf(time.Duration(0))
+	timeTimeType        = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 )
 
 func getOrCreateFlags() Flags {
@@ -139,64 +320,374 @@ func getOrCreateFlags() Flags {
 // StructVar defines the fields of a struct as flags.
 // structPtr must be a pointer to a struct.
 // Anonoymous embedded fields are flattened.
+// The fields of named struct fields are defined with the
+// name of the struct field and NestedNameSeparator as prefix,
+// see PrefixTag.
 // Struct fields with NameTag of "-" will be ignored.
+// The DefaultTag is applied to fields with a zero value
+// and to pointer fields, the current values of all other
+// fields are used as flag defaults.
+// Panics if a field can't be defined as flag, see StructVarE.
 func StructVar(structPtr interface{}) {
-	structVar(structPtr, getOrCreateFlags(), true)
+	err := StructVarE(structPtr)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// StructVarE defines the fields of a struct as flags like StructVar,
+// but returns an error instead of panicking.
+// If fields can't be defined as flag, then no flag is defined
+// and the errors of all fields are returned together as FieldErrors.
+// Default values of the other fields may already be set in that case.
+func StructVarE(structPtr interface{}) error {
+	err := checkStructPtr(structPtr)
+	if err != nil {
+		return err
+	}
+	rel := newFlagRelations(reflect.TypeOf(structPtr))
+	sources := newValueSources()
+	// Define the flags on new Flags first
+	// so that no flag is defined in case of an error
+	structFlags := NewFlags()
+	errs := structVar(structPtr, "", nil, rel, sources, structFlags, zeroValueDefaults, nil)
+	if len(errs) > 0 {
+		return errs
+	}
+	if !copyFlags(getOrCreateFlags(), structFlags) {
+		// Flags without VisitAll are defined directly
+		structVar(structPtr, "", nil, rel, sources, getOrCreateFlags(), zeroValueDefaults, nil)
+	}
+	boundStructs = append(boundStructs, boundStruct{structPtr, sources})
+	return nil
+}
+
+// copyFlags defines the flags of src also on dst
+// or returns false if src doesn't support VisitAll.
+func copyFlags(dst, src Flags) bool {
+	visitor, ok := src.(interface{ VisitAll(func(*pflag.Flag)) })
+	if !ok {
+		return false
+	}
+	dstp, _ := dst.(FlagsP)
+	visitor.VisitAll(func(flag *pflag.Flag) {
+		if flag.Shorthand != "" && dstp != nil {
+			dstp.VarP(flag.Value, flag.Name, flag.Shorthand, flag.Usage)
+		} else {
+			dst.Var(flag.Value, flag.Name, flag.Usage)
+		}
+	})
+	return true
 }
 
-func structVar(structPtr interface{}, flags Flags, fieldValuesAsDefault bool) {
+func checkStructPtr(structPtr interface{}) error {
+	v := reflect.ValueOf(structPtr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected non nil pointer to a struct, but got %T", structPtr)
+	}
+	return nil
+}
+
+// defaultMode defines when structVar applies the DefaultTag of a field
+type defaultMode int
+
+const (
+	// fieldValueDefaults ignores DefaultTag and uses the
+	// current field values as flag defaults, used to define
+	// the flags again after values have been loaded
+	fieldValueDefaults defaultMode = iota
+	// zeroValueDefaults applies DefaultTag to fields
+	// with a zero value and to pointer fields
+	zeroValueDefaults
+	// tagDefaults always applies DefaultTag
+	tagDefaults
+)
+
+// structVar defines the fields of structPtr as flags
+// with namePrefix prepended to the flag names.
+// The DefaultTag of the fields is applied as defined by defaults.
+// If negatableFlags is not nil, then it contains the names of the
+// negatable bool flags instead of deciding by NegatableTag
+// and the default value.
+// If alloc is not nil, then structPtr points to lazily allocated memory
+// and alloc is called when a flag of the struct is set.
+// The relationships between flags are passed as rel
+// and the sources of set flag values are tracked in sources.
+func structVar(structPtr interface{}, namePrefix string, alloc func(), rel *flagRelations, sources *valueSources, flags Flags, defaults defaultMode, negatableFlags map[string]bool) (errs FieldErrors) {
 	flagsp, _ := flags.(FlagsP)
-	var err error
 	for _, f := range reflection.FlatExportedStructFields(structPtr) {
-		name := f.Field.Tag.Get(NameTag)
-		if name == "-" {
+		fieldName, ok := fieldFlagName(f.Field)
+		if !ok {
 			continue
 		}
-		if name == "" {
-			name = f.Field.Name
-		}
-		name = NameFunc(name)
+		name := NameFunc(namePrefix + fieldName)
 
 		shorthand, hasShorthand := f.Field.Tag.Lookup(ShorthandTag)
 		hasShorthand = hasShorthand && (flagsp != nil)
 
 		usage := f.Field.Tag.Get(UsageTag)
 
-		if f.Field.Type.Implements(pflagValueType) {
-			val := f.Value.Addr().Interface().(pflag.Value)
-			if hasShorthand {
-				flagsp.VarP(val, name, shorthand, usage)
-			} else {
-				flags.Var(val, name, usage)
-			}
+		required, err := isRequired(f.Field)
+		if err != nil {
+			errs = append(errs, newFieldError(f.Field, name, RequiredTag, err))
 			continue
 		}
+		if required {
+			usage = strings.TrimSpace(usage + " (required)")
+		}
+		usage = strings.TrimSpace(usage + " " + rel.usage(f.Field, name))
+
+		aliases := fieldFlagAliases(f.Field)
+		for i := range aliases {
+			aliases[i] = NameFunc(namePrefix + aliases[i])
+		}
+		if len(aliases) > 0 {
+			usage = strings.TrimSpace(usage + " (aliases: --" + strings.Join(aliases, ", --") + ")")
+		}
+		if env := fieldEnvName(f.Field, name); env != "" {
+			usage = strings.TrimSpace(usage + " (env: " + env + ")")
+		}
+
+		hidden := false
+		if hiddenStr, ok := f.Field.Tag.Lookup(HiddenTag); ok {
+			hidden, err = strconv.ParseBool(hiddenStr)
+			if err != nil {
+				errs = append(errs, newFieldError(f.Field, name, HiddenTag, err))
+				continue
+			}
+		}
+		deprecated := f.Field.Tag.Get(DeprecatedTag)
+		secret := isSecret(f.Field)
 
 		defaultStr, hasDefault := f.Field.Tag.Lookup(DefaultTag)
+		useDefault := hasDefault && (defaults == tagDefaults ||
+			defaults == zeroValueDefaults && (f.Field.Type.Kind() == reflect.Ptr || f.Value.IsZero()))
 
 		fieldType := f.Field.Type
 		fieldValue := f.Value
+		fieldAlloc := alloc
+		fieldDefaults := defaults
+
+		negatable := false
+
+		varFlag := func(val pflag.Value) {
+			val = &sourceValue{Value: val, sources: sources, name: name}
+			if fieldAlloc != nil {
+				val = &allocValue{Value: val, alloc: fieldAlloc}
+			}
+			if negatable {
+				var negative pflag.Value
+				val, negative = newNegatableValues(val, sources, name)
+				if hidden || deprecated != "" {
+					negative = &hiddenValue{Value: negative, name: NegationPrefix + name, deprecated: deprecated}
+				}
+				flags.Var(negative, NegationPrefix+name, "negates --"+name)
+			}
+			if secret {
+				val = &secretValue{val}
+			}
+			// hiddenValue has to be the outermost wrapper
+			// so that printDefaults recognizes it
+			if hidden || deprecated != "" {
+				val = &hiddenValue{Value: val, name: name, deprecated: deprecated}
+			}
+			if hasShorthand {
+				flagsp.VarP(val, name, shorthand, usage)
+			} else {
+				flags.Var(val, name, usage)
+			}
+			for _, alias := range aliases {
+				flags.Var(&aliasValue{val}, alias, "alias for --"+name)
+			}
+		}
 
 		isPtr := fieldType.Kind() == reflect.Ptr
 		if isPtr {
 			if fieldValue.IsNil() {
-				err = fmt.Errorf("pointer struct field '%s' must not be nil", f.Field.Name)
-				panic(err)
+				if !AllowNilPointers {
+					err = errors.New("pointer struct field must not be nil")
+					errs = append(errs, newFieldError(f.Field, name, "", err))
+					continue
+				}
+				if useDefault {
+					// The default value will be set, so the field can be
+					// allocated right away, but a lazily allocated parent
+					// struct is still only allocated when a flag is set
+					fieldValue.Set(reflect.New(fieldType.Elem()))
+				} else {
+					fieldValue, fieldAlloc = allocOnSet(fieldValue, alloc)
+					// The new memory has no values yet,
+					// so apply the DefaultTag of nested fields
+					fieldDefaults = tagDefaults
+				}
 			}
 			fieldType = fieldType.Elem()
 			fieldValue = fieldValue.Elem()
-			fieldValuesAsDefault = !hasDefault
+		}
+
+		if reflect.PtrTo(fieldType).Implements(pflagValueType) {
+			val := fieldValue.Addr().Interface().(pflag.Value)
+			if useDefault {
+				err = val.Set(defaultStr)
+				if err != nil {
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
+				}
+			}
+			varFlag(val)
+			continue
+		}
+
+		if fieldType == timeTimeType {
+			layout := f.Field.Tag.Get(LayoutTag)
+			if layout == "" {
+				layout = TimeLayout
+			}
+			location := TimeLocation
+			if locationName, ok := f.Field.Tag.Lookup(LocationTag); ok {
+				location, err = time.LoadLocation(locationName)
+				if err != nil {
+					errs = append(errs, newFieldError(f.Field, name, LocationTag, err))
+					continue
+				}
+			}
+			val := newTimeValue(fieldValue, layout, location)
+			if useDefault {
+				err = val.Set(defaultStr)
+				if err != nil {
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
+				}
+			}
+			usage = strings.TrimSpace(usage + " (layout: " + layout + ")")
+			varFlag(val)
+			continue
+		}
+
+		if isTextType(fieldType) {
+			if useDefault {
+				err = fieldValue.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(defaultStr))
+				if err != nil {
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
+				}
+			}
+			val := newTextValue(fieldValue)
+			varFlag(val)
+			continue
+		}
+
+		if fieldType.Kind() == reflect.Struct {
+			prefix := namePrefix + nestedNamePrefix(f.Field, fieldName)
+			errs = append(errs, structVar(fieldValue, prefix, fieldAlloc, rel, sources, flags, fieldDefaults, negatableFlags)...)
+			continue
+		}
+
+		if countStr, hasCount := f.Field.Tag.Lookup(CountTag); hasCount {
+			count, err := strconv.ParseBool(countStr)
+			if err == nil && count && !isCounterType(fieldType) {
+				err = fmt.Errorf("count not supported for type %s", fieldType)
+			}
+			if err != nil {
+				errs = append(errs, newFieldError(f.Field, name, CountTag, err))
+				continue
+			}
+			if count {
+				val := newCounterValue(fieldValue)
+				if useDefault {
+					err = val.Set(defaultStr)
+					if err != nil {
+						errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+						continue
+					}
+				}
+				varFlag(val)
+				continue
+			}
+		}
+
+		if enumStr, hasEnum := f.Field.Tag.Lookup(EnumTag); hasEnum {
+			allowed, err := parseEnum(fieldType, enumStr)
+			if err != nil {
+				errs = append(errs, newFieldError(f.Field, name, EnumTag, err))
+				continue
+			}
+			val := newEnumValue(fieldValue, allowed)
+			if useDefault {
+				err = val.Set(defaultStr)
+				if err != nil {
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
+				}
+			}
+			usage = strings.TrimSpace(usage + " (one of: " + formatEnum(allowed) + ")")
+			varFlag(val)
+			continue
+		}
+
+		if fieldType.Kind() == reflect.Bool {
+			// Bools are negatable per default if they default to true
+			var defaultValue reflect.Value
+			if useDefault {
+				defaultValue, err = parseScalar(fieldType, defaultStr)
+				if err != nil {
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
+				}
+				negatable = defaultValue.Bool()
+			} else {
+				negatable = fieldValue.Bool()
+			}
+			if negatableStr, ok := f.Field.Tag.Lookup(NegatableTag); ok {
+				negatable, err = strconv.ParseBool(negatableStr)
+				if err != nil {
+					errs = append(errs, newFieldError(f.Field, name, NegatableTag, err))
+					continue
+				}
+			}
+			if negatableFlags != nil {
+				// The field value may have been loaded after
+				// the flag was defined with its default value
+				negatable = negatableFlags[name]
+			}
+			if negatable {
+				if defaultValue.IsValid() {
+					fieldValue.Set(defaultValue)
+				}
+				varFlag(newScalarValue(fieldValue))
+				continue
+			}
+		}
+
+		isNamedType := fieldType.Name() != fieldType.Kind().String() && fieldType != timeDurationType
+		useValue := fieldAlloc != nil || isNamedType || required || hidden || deprecated != "" || secret || len(aliases) > 0 || rel.related[name]
+		if useValue && isScalarType(fieldType) {
+			// The typed Flags methods would set the value without
+			// allocating the memory, tracking that it was set
+			// or hiding it in the usage, and don't support
+			// named types, so use a Value instead
+			if useDefault {
+				value, err := parseScalar(fieldType, defaultStr)
+				if err != nil {
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
+				}
+				fieldValue.Set(value)
+			}
+			varFlag(newScalarValue(fieldValue))
+			continue
 		}
 
 		if fieldType == timeDurationType {
 			var value time.Duration
-			if fieldValuesAsDefault {
-				value = fieldValue.Interface().(time.Duration)
-			} else if hasDefault {
+			if useDefault {
 				value, err = time.ParseDuration(defaultStr)
 				if err != nil {
-					panic(err)
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
 				}
+			} else {
+				value = fieldValue.Interface().(time.Duration)
 			}
 			ptr := fieldValue.Addr().Interface().(*time.Duration)
 			if hasShorthand {
@@ -210,13 +701,14 @@ func structVar(structPtr interface{}, flags Flags, fieldValuesAsDefault bool) {
 		switch fieldType.Kind() {
 		case reflect.Bool:
 			var value bool
-			if fieldValuesAsDefault {
-				value = fieldValue.Interface().(bool)
-			} else if hasDefault {
+			if useDefault {
 				value, err = strconv.ParseBool(defaultStr)
 				if err != nil {
-					panic(err)
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
 				}
+			} else {
+				value = fieldValue.Interface().(bool)
 			}
 			ptr := fieldValue.Addr().Interface().(*bool)
 			if hasShorthand {
@@ -227,13 +719,14 @@ func structVar(structPtr interface{}, flags Flags, fieldValuesAsDefault bool) {
 
 		case reflect.Float64:
 			var value float64
-			if fieldValuesAsDefault {
-				value = fieldValue.Interface().(float64)
-			} else if hasDefault {
+			if useDefault {
 				value, err = strconv.ParseFloat(defaultStr, 64)
 				if err != nil {
-					panic(err)
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
 				}
+			} else {
+				value = fieldValue.Interface().(float64)
 			}
 			ptr := fieldValue.Addr().Interface().(*float64)
 			if hasShorthand {
@@ -244,13 +737,14 @@ func structVar(structPtr interface{}, flags Flags, fieldValuesAsDefault bool) {
 
 		case reflect.Int64:
 			var value int64
-			if fieldValuesAsDefault {
-				value = fieldValue.Interface().(int64)
-			} else if hasDefault {
+			if useDefault {
 				value, err = strconv.ParseInt(defaultStr, 0, 64)
 				if err != nil {
-					panic(err)
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
 				}
+			} else {
+				value = fieldValue.Interface().(int64)
 			}
 			ptr := fieldValue.Addr().Interface().(*int64)
 			if hasShorthand {
@@ -261,13 +755,14 @@ func structVar(structPtr interface{}, flags Flags, fieldValuesAsDefault bool) {
 
 		case reflect.Int:
 			var value int64
-			if fieldValuesAsDefault {
-				value = int64(fieldValue.Interface().(int))
-			} else if hasDefault {
+			if useDefault {
 				value, err = strconv.ParseInt(defaultStr, 0, 64)
 				if err != nil {
-					panic(err)
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
 				}
+			} else {
+				value = int64(fieldValue.Interface().(int))
 			}
 			ptr := fieldValue.Addr().Interface().(*int)
 			if hasShorthand {
@@ -278,10 +773,10 @@ func structVar(structPtr interface{}, flags Flags, fieldValuesAsDefault bool) {
 
 		case reflect.String:
 			var value string
-			if fieldValuesAsDefault {
-				value = fieldValue.Interface().(string)
-			} else if hasDefault {
+			if useDefault {
 				value = defaultStr
+			} else {
+				value = fieldValue.Interface().(string)
 			}
 			ptr := fieldValue.Addr().Interface().(*string)
 			if hasShorthand {
@@ -292,13 +787,14 @@ func structVar(structPtr interface{}, flags Flags, fieldValuesAsDefault bool) {
 
 		case reflect.Uint64:
 			var value uint64
-			if fieldValuesAsDefault {
-				value = fieldValue.Interface().(uint64)
-			} else if hasDefault {
+			if useDefault {
 				value, err = strconv.ParseUint(defaultStr, 0, 64)
 				if err != nil {
-					panic(err)
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
 				}
+			} else {
+				value = fieldValue.Interface().(uint64)
 			}
 			ptr := fieldValue.Addr().Interface().(*uint64)
 			if hasShorthand {
@@ -309,13 +805,14 @@ func structVar(structPtr interface{}, flags Flags, fieldValuesAsDefault bool) {
 
 		case reflect.Uint:
 			var value uint64
-			if fieldValuesAsDefault {
-				value = uint64(fieldValue.Interface().(uint))
-			} else if hasDefault {
+			if useDefault {
 				value, err = strconv.ParseUint(defaultStr, 0, 64)
 				if err != nil {
-					panic(err)
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
 				}
+			} else {
+				value = uint64(fieldValue.Interface().(uint))
 			}
 			ptr := fieldValue.Addr().Interface().(*uint)
 			if hasShorthand {
@@ -323,13 +820,111 @@ func structVar(structPtr interface{}, flags Flags, fieldValuesAsDefault bool) {
 			} else {
 				flags.UintVar(ptr, name, uint(value), usage)
 			}
+
+		case reflect.Slice:
+			if !isScalarType(fieldType.Elem()) {
+				if Strict {
+					errs = append(errs, newFieldError(f.Field, name, "", errUnsupportedType(f.Field.Type)))
+				}
+				continue
+			}
+			if useDefault {
+				value, err := parseSlice(fieldType, defaultStr)
+				if err != nil {
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
+				}
+				fieldValue.Set(value)
+			}
+			val := newSliceValue(fieldValue)
+			varFlag(val)
+
+		case reflect.Map:
+			if !isMapType(fieldType) {
+				if Strict {
+					errs = append(errs, newFieldError(f.Field, name, "", errUnsupportedType(f.Field.Type)))
+				}
+				continue
+			}
+			if useDefault {
+				value, err := parseMap(fieldType, defaultStr)
+				if err != nil {
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
+				}
+				fieldValue.Set(value)
+			}
+			val := newMapValue(fieldValue)
+			varFlag(val)
+
+		case reflect.Int8, reflect.Int16, reflect.Int32,
+			reflect.Uint8, reflect.Uint16, reflect.Uint32,
+			reflect.Float32:
+			if useDefault {
+				value, err := parseScalar(fieldType, defaultStr)
+				if err != nil {
+					errs = append(errs, newFieldError(f.Field, name, DefaultTag, err))
+					continue
+				}
+				fieldValue.Set(value)
+			}
+			val := newScalarValue(fieldValue)
+			varFlag(val)
+
+		default:
+			if Strict {
+				errs = append(errs, newFieldError(f.Field, name, "", errUnsupportedType(f.Field.Type)))
+			}
 		}
 	}
+	return errs
+}
+
+// fieldFlagName returns the flag name of a struct field
+// without NameFunc applied, or false if the field is ignored
+// because of a NameTag of "-".
+// Aliases following the name in the NameTag are not returned.
+func fieldFlagName(field reflect.StructField) (name string, ok bool) {
+	name, _, _ = strings.Cut(field.Tag.Get(NameTag), ",")
+	name = strings.TrimSpace(name)
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// nestedNamePrefix returns the flag name prefix
+// for the fields of the named struct field.
+func nestedNamePrefix(field reflect.StructField, fieldName string) string {
+	prefix, hasPrefix := field.Tag.Lookup(PrefixTag)
+	if !hasPrefix {
+		prefix = fieldName
+	}
+	if prefix != "" {
+		prefix += NestedNameSeparator
+	}
+	return prefix
 }
 
 // Parse parses args, or if no args are given os.Args[1:]
+// and then checks all structs passed to StructVar for missing
+// required fields, violated flag relationships and with Validate.
 func Parse(args ...string) ([]string, error) {
-	return parse(args, getOrCreateFlags())
+	args, err := parse(args, getOrCreateFlags())
+	if err != nil {
+		return nil, err
+	}
+	var errs FieldErrors
+	for _, b := range boundStructs {
+		errs = append(errs, checkParsed(b.structPtr, b.sources)...)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return args, nil
 }
 
 func parse(args []string, flags Flags) ([]string, error) {
@@ -359,7 +954,7 @@ func PrintUsageTo(output io.Writer) {
 		PrintUsageIntro(output)
 	}
 	if flags != nil {
-		flags.PrintDefaults()
+		printDefaults(flags)
 	}
 }
 
@@ -395,34 +990,90 @@ func MustParseCommandLine() []string {
 // value loaded from the configuration file.
 // Values not present in the command line won't effect the Values
 // loaded from the configuration file.
+// Environment variables defined by EnvTag or EnvPrefix are applied
+// after the configuration file and before the command line.
+// Slice and map values from the command line replace the ones loaded
+// from the configuration file unless AppendSliceFlags is true.
 // If there is an error loading the configuration file,
 // then the command line still gets parsed.
+// After parsing the struct is checked for missing required fields,
+// violated flag relationships and with Validate,
+// unless the configuration file could not be loaded
+// for another reason than not existing.
 // An error where os.IsNotExist(err) == true can be ignored
 // if the existence of the configuration file is optional.
 func LoadFileAndParseCommandLine(filename string, structPtr interface{}) ([]string, error) {
 	// Initialize global variable set with unchanged default values
 	// so that a later PrintDefaults() prints the correct default values.
-	StructVar(structPtr)
+	err := StructVarE(structPtr)
+	if err != nil {
+		return nil, err
+	}
+	return loadFileAndParse(filename, structPtr, nil, getOrCreateFlags())
+}
+
+// loadFileAndParse implements LoadFileAndParseCommandLine for args,
+// or for os.Args[1:] if args is nil, after the flags of structPtr
+// have been defined on definedFlags and checked for field errors.
+// No file is loaded if filename is empty.
+// The defaults of definedFlags are printed
+// as usage in case of a parse error.
+func loadFileAndParse(filename string, structPtr interface{}, args []string, definedFlags Flags) ([]string, error) {
+	// The sources of the values of this call are
+	// tracked for checking required fields and relations
+	sources := newValueSources()
+
+	// Bool flags stay negatable if their loaded value
+	// differs from the default they were defined with
+	negatable := negatableFlagNames(definedFlags)
 
 	// Load and unmarshal struct from file
-	loadErr := LoadFile(filename, structPtr)
+	var loadErr error
+	if filename != "" {
+		loadErr = loadFile(filename, structPtr, sources)
+	}
+
+	// Environment variables overwrite the values from the file
+	err := loadEnv(structPtr, sources)
+	if err != nil {
+		return nil, err
+	}
 
 	// Use the existing struct values as defaults for tempSet
 	// so that not existing args don't overwrite existing values
 	// that have been loaded from the confriguration file
+	// Field errors have already been returned by the caller.
 	tempFlags := NewFlags()
-	structVar(structPtr, tempFlags, true)
-
-	// If called by a test, then return without parsing args
-	// because the "-test" flag syntax is not supported
-	if len(os.Args) > 1 && strings.HasPrefix(os.Args[1], "-test") {
-		return nil, loadErr
+	structVar(structPtr, "", nil, newFlagRelations(reflect.TypeOf(structPtr)), sources, tempFlags, fieldValueDefaults, negatable)
+	if flagSet, ok := tempFlags.(*pflag.FlagSet); ok {
+		flagSet.Usage = func() {
+			if PrintUsageIntro != nil {
+				PrintUsageIntro(Output)
+			}
+			printDefaults(definedFlags)
+		}
+	}
+	if args == nil {
+		// If called by a test, then return without parsing args
+		// because the "-test" flag syntax is not supported
+		if len(os.Args) > 1 && strings.HasPrefix(os.Args[1], "-test") {
+			return nil, loadErr
+		}
+		args = os.Args[1:]
 	}
 
-	err := tempFlags.Parse(os.Args[1:])
+	err = tempFlags.Parse(args)
 	if err != nil {
 		return nil, err
 	}
+	// Values of a file that could not be loaded are missing
+	// for the checks, so the load error is the cause
+	if loadErr != nil && !os.IsNotExist(loadErr) {
+		return tempFlags.Args(), loadErr
+	}
+	if errs := checkParsed(structPtr, sources); len(errs) > 0 {
+		return nil, errs
+	}
 	return tempFlags.Args(), loadErr
 }
 
@@ -445,12 +1096,42 @@ func LoadFileIfExistsAndMustParseCommandLine(filename string, structPtr interfac
 }
 
 // PrintConfig prints the flattened struct fields from structPtr to Output.
+// The fields of nested structs are printed with the
+// struct field name and a dot as prefix.
+// Non zero values of fields with a SecretTag are printed as SecretMask.
 func PrintConfig(structPtr interface{}) {
+	printConfig(structPtr, "")
+}
+
+func printConfig(structPtr interface{}, namePrefix string) {
 	for _, f := range reflection.FlatExportedStructFields(structPtr) {
+		name := namePrefix + f.Field.Name
 		v := f.Value
-		for v.Kind() == reflect.Ptr {
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
 			v = v.Elem()
 		}
-		fmt.Fprintf(Output, "%s: %v\n", f.Field.Name, v.Interface())
+		switch {
+		case isSecret(f.Field) && !v.IsZero():
+			fmt.Fprintf(Output, "%s: %s\n", name, SecretMask)
+		case v.Kind() != reflect.Ptr && isNestedStructType(v.Type()):
+			printConfig(v, name+".")
+		default:
+			fmt.Fprintf(Output, "%s: %v\n", name, v.Interface())
+		}
+	}
+}
+
+// isSecret returns if field has a SecretTag with a true value
+func isSecret(field reflect.StructField) bool {
+	secret, _ := strconv.ParseBool(field.Tag.Get(SecretTag))
+	return secret
+}
+
+// maskedValue returns SecretMask instead of value
+// if field has a SecretTag for use in error messages.
+func maskedValue(field reflect.StructField, value interface{}) interface{} {
+	if isSecret(field) {
+		return SecretMask
 	}
+	return value
 }
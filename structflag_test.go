@@ -0,0 +1,408 @@
+package structflag
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ogier/pflag"
+)
+
+// resetFlags resets the package level flags and bound structs
+// and sets OnParseError to pflag.ContinueOnError for a test.
+func resetFlags(t *testing.T) {
+	t.Helper()
+	oldOnParseError, oldOutput := OnParseError, Output
+	OnParseError, Output = pflag.ContinueOnError, io.Discard
+	flags, boundStructs = nil, nil
+	t.Cleanup(func() {
+		OnParseError, Output = oldOnParseError, oldOutput
+		flags, boundStructs = nil, nil
+	})
+}
+
+// lookupFlag returns the flag name of the package level flags
+func lookupFlag(t *testing.T, name string) *pflag.Flag {
+	t.Helper()
+	flag := getOrCreateFlags().(*pflag.FlagSet).Lookup(name)
+	if flag == nil {
+		t.Fatalf("flag --%s not defined", name)
+	}
+	return flag
+}
+
+type defaultsConfig struct {
+	Port  uint16  `default:"8080"`
+	Ratio float32 `default:"0.5"`
+	Name  string  `default:"test"`
+	Set   int     `default:"1"`
+	Ptr   *int    `default:"8080"`
+	Plain int
+}
+
+func TestStructVarAppliesDefaults(t *testing.T) {
+	resetFlags(t)
+	ptr := 1
+	config := defaultsConfig{Set: 2, Ptr: &ptr, Plain: 3}
+	err := StructVarE(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Parse("--Ratio=0.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Port != 8080 || config.Ratio != 0.25 || config.Name != "test" || config.Set != 2 || *config.Ptr != 8080 || config.Plain != 3 {
+		t.Errorf("StructVar() = %+v with Ptr %d", config, *config.Ptr)
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Port", "8080"},
+		{"Ratio", "0.5"},
+		{"Name", "test"},
+		{"Set", "2"},
+		{"Ptr", "8080"},
+		{"Plain", "3"},
+	}
+	for _, tt := range tests {
+		if got := lookupFlag(t, tt.name).DefValue; got != tt.want {
+			t.Errorf("default of --%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStructVarRejectsInvalidDefaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		structPtr interface{}
+	}{
+		{"uint16 out of range", &struct {
+			Port uint16 `default:"70000"`
+		}{}},
+		{"negative uint", &struct {
+			Size uint `default:"-1"`
+		}{}},
+		{"int8 out of range", &struct {
+			Level int8 `default:"128"`
+		}{}},
+		{"float32 out of range", &struct {
+			Ratio float32 `default:"1e40"`
+		}{}},
+		{"pointer out of range", &struct {
+			Port *uint8 `default:"256"`
+		}{Port: new(uint8)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			err := StructVarE(tt.structPtr)
+			if err == nil {
+				t.Error("StructVarE() with invalid default, want error")
+			}
+		})
+	}
+}
+
+type nilPointerDatabase struct {
+	Host    string `default:"localhost"`
+	Port    int
+	Timeout *int `default:"5"`
+}
+
+type nilPointerConfig struct {
+	Name *string
+	Port *int `default:"80"`
+	DB   *nilPointerDatabase
+}
+
+func TestAllowNilPointers(t *testing.T) {
+	AllowNilPointers = true
+	t.Cleanup(func() { AllowNilPointers = false })
+
+	five := 5
+	tests := []struct {
+		name   string
+		args   []string
+		want   nilPointerConfig
+		wantDB *nilPointerDatabase
+	}{
+		{
+			name: "no flags",
+			want: nilPointerConfig{Port: intPtr(80)},
+		},
+		{
+			name: "scalar flag",
+			args: []string{"--Name=x", "--Port=8080"},
+			want: nilPointerConfig{Name: stringPtr("x"), Port: intPtr(8080)},
+		},
+		{
+			name:   "nested flag",
+			args:   []string{"--DB.Port=3"},
+			want:   nilPointerConfig{Port: intPtr(80)},
+			wantDB: &nilPointerDatabase{Host: "localhost", Port: 3, Timeout: &five},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			config, _, err := Bind[nilPointerConfig](WithArgs(tt.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalPtr(config.Name, tt.want.Name) || !equalPtr(config.Port, tt.want.Port) {
+				t.Errorf("Bind() = %+v, want %+v", config, tt.want)
+			}
+			switch {
+			case tt.wantDB == nil && config.DB != nil:
+				t.Errorf("Bind() DB = %+v, want nil", config.DB)
+			case tt.wantDB != nil && config.DB == nil:
+				t.Errorf("Bind() DB = nil, want %+v", tt.wantDB)
+			case tt.wantDB != nil:
+				if config.DB.Host != tt.wantDB.Host || config.DB.Port != tt.wantDB.Port || !equalPtr(config.DB.Timeout, tt.wantDB.Timeout) {
+					t.Errorf("Bind() DB = %+v, want %+v", config.DB, tt.wantDB)
+				}
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int { return &i }
+
+func stringPtr(s string) *string { return &s }
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func TestStructVarEReturnsFieldErrors(t *testing.T) {
+	resetFlags(t)
+	invalid := struct {
+		Name  string
+		Count int    `default:"abc"`
+		Ptr   *int   `default:"1"`
+		Mode  string `enum:"a,b" default:"c"`
+	}{}
+	err := StructVarE(&invalid)
+	errs, ok := err.(FieldErrors)
+	if !ok {
+		t.Fatalf("StructVarE() = %v, want FieldErrors", err)
+	}
+	tests := []struct {
+		field string
+		tag   string
+	}{
+		{"Count", DefaultTag},
+		{"Ptr", ""},
+		{"Mode", DefaultTag},
+	}
+	if len(errs) != len(tests) {
+		t.Fatalf("StructVarE() = %d errors, want %d: %s", len(errs), len(tests), errs)
+	}
+	for i, tt := range tests {
+		if errs[i].Field != tt.field || errs[i].Tag != tt.tag {
+			t.Errorf("error %d = %s, want field %s with tag %q", i, errs[i], tt.field, tt.tag)
+		}
+	}
+	if flag := getOrCreateFlags().(*pflag.FlagSet).Lookup("Name"); flag != nil {
+		t.Error("StructVarE() with errors defined flag --Name")
+	}
+
+	// No flag redefined panic after an error
+	valid := struct {
+		Name  string
+		Count int `default:"1"`
+	}{}
+	err = StructVarE(&valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid.Count != 1 {
+		t.Errorf("StructVarE() Count = %d, want 1", valid.Count)
+	}
+}
+
+func TestStructVarValueDefaults(t *testing.T) {
+	resetFlags(t)
+	config := struct {
+		Size  ByteSize `default:"512KiB"`
+		Share Percent  `default:"75%"`
+		Mode  FileMode `default:"0644"`
+		Limit ByteSize `default:"1KiB"`
+	}{Limit: 2 * KiB}
+	err := StructVarE(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Size != 512*KiB || config.Share != 75 || config.Mode != 0644 || config.Limit != 2*KiB {
+		t.Errorf("StructVarE() = %+v", config)
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Size", "512KiB"},
+		{"Share", "75%"},
+		{"Mode", "0644"},
+		{"Limit", "2KiB"},
+	}
+	for _, tt := range tests {
+		if got := lookupFlag(t, tt.name).DefValue; got != tt.want {
+			t.Errorf("default of --%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	resetFlags(t)
+	invalid := struct {
+		Size ByteSize `default:"-1KiB"`
+	}{}
+	err = StructVarE(&invalid)
+	if errs, ok := err.(FieldErrors); !ok || len(errs) != 1 || errs[0].Tag != DefaultTag {
+		t.Errorf("StructVarE() with malformed default = %v, want FieldErrors for %s", err, DefaultTag)
+	}
+}
+
+type printConfigDatabase struct {
+	Host     string
+	Password string `secret:"true"`
+}
+
+type printConfigConfig struct {
+	Verbose bool   `flag:"verbose"`
+	Ignored string `flag:"-"`
+	Token   string `secret:"true"`
+	Empty   string `secret:"true"`
+	DB      printConfigDatabase
+	Opt     *printConfigDatabase
+}
+
+func TestPrintConfig(t *testing.T) {
+	var buf bytes.Buffer
+	output := Output
+	Output = &buf
+	t.Cleanup(func() { Output = output })
+
+	PrintConfig(&printConfigConfig{
+		Verbose: true,
+		Ignored: "x",
+		Token:   "token",
+		DB:      printConfigDatabase{Host: "localhost", Password: "password"},
+	})
+	want := "Verbose: true\n" +
+		"Ignored: x\n" +
+		"Token: ******\n" +
+		"Empty: \n" +
+		"DB.Host: localhost\n" +
+		"DB.Password: ******\n" +
+		"Opt: <nil>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("PrintConfig() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+type nestedDatabase struct {
+	Host string `flag:"host"`
+	Port int    `flag:"port"`
+}
+
+type nestedEmbedded struct {
+	Debug bool `flag:"debug"`
+}
+
+type nestedConfig struct {
+	nestedEmbedded
+	DB      nestedDatabase `flag:"db"`
+	Cache   nestedDatabase `flag:"cache" prefix:"redis"`
+	Plain   nestedDatabase `prefix:""`
+	Ignored nestedDatabase `flag:"-"`
+}
+
+func TestNestedStructFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		separator string
+		args      []string
+	}{
+		{
+			name:      "dot separator",
+			separator: ".",
+			args:      []string{"--debug", "--db.host=a", "--db.port=1", "--redis.host=b", "--host=c"},
+		},
+		{
+			name:      "dash separator",
+			separator: "-",
+			args:      []string{"--debug", "--db-host=a", "--db-port=1", "--redis-host=b", "--host=c"},
+		},
+	}
+	want := nestedConfig{
+		nestedEmbedded: nestedEmbedded{Debug: true},
+		DB:             nestedDatabase{Host: "a", Port: 1},
+		Cache:          nestedDatabase{Host: "b"},
+		Plain:          nestedDatabase{Host: "c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			NestedNameSeparator = tt.separator
+			defer func() { NestedNameSeparator = "." }()
+			config, _, err := Bind[nestedConfig](WithArgs(tt.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *config != want {
+				t.Errorf("Bind() = %+v, want %+v", *config, want)
+			}
+		})
+	}
+
+	resetFlags(t)
+	_, _, err := Bind[nestedConfig](WithArgs("--Ignored.host=x"))
+	if err == nil {
+		t.Error("Bind() with flag of ignored nested struct, want error")
+	}
+}
+
+func TestStrict(t *testing.T) {
+	type unsupported struct {
+		Complex   complex128
+		Chan      chan int
+		Func      func()
+		Array     [2]int
+		Interface interface{}
+		Structs   []nestedDatabase
+		Ignored   chan int `flag:"-"`
+		Name      string
+	}
+	tests := []struct {
+		strict     bool
+		wantFields []string
+	}{
+		{strict: false},
+		{strict: true, wantFields: []string{"Complex", "Chan", "Func", "Array", "Interface", "Structs"}},
+	}
+	for _, tt := range tests {
+		resetFlags(t)
+		Strict = tt.strict
+		err := StructVarE(&unsupported{})
+		Strict = false
+		if len(tt.wantFields) == 0 {
+			if err != nil {
+				t.Errorf("StructVarE() with Strict %t error: %s", tt.strict, err)
+			}
+			continue
+		}
+		errs, ok := err.(FieldErrors)
+		if !ok || len(errs) != len(tt.wantFields) {
+			t.Fatalf("StructVarE() with Strict %t = %v, want errors for %v", tt.strict, err, tt.wantFields)
+		}
+		for i, field := range tt.wantFields {
+			if errs[i].Field != field {
+				t.Errorf("error %d = %s, want field %s", i, errs[i], field)
+			}
+		}
+	}
+}
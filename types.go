@@ -0,0 +1,218 @@
+package structflag
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes that implements pflag.Value,
+// encoding.TextMarshaler and encoding.TextUnmarshaler.
+// It is parsed from strings like "512KiB", "10MB" or "1024"
+// with decimal units (kB, MB, GB, TB, PB, EB)
+// and binary units (KiB, MiB, GiB, TiB, PiB, EiB).
+type ByteSize uint64
+
+// Decimal and binary byte size units
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000 * Byte
+	MB ByteSize = 1000 * KB
+	GB ByteSize = 1000 * MB
+	TB ByteSize = 1000 * GB
+	PB ByteSize = 1000 * TB
+	EB ByteSize = 1000 * PB
+
+	KiB ByteSize = 1024 * Byte
+	MiB ByteSize = 1024 * KiB
+	GiB ByteSize = 1024 * MiB
+	TiB ByteSize = 1024 * GiB
+	PiB ByteSize = 1024 * TiB
+	EiB ByteSize = 1024 * PiB
+)
+
+var byteSizeUnits = []struct {
+	name string
+	size ByteSize
+}{
+	// Ordered from large to small, binary before decimal
+	{"EiB", EiB}, {"PiB", PiB}, {"TiB", TiB}, {"GiB", GiB}, {"MiB", MiB}, {"KiB", KiB},
+	{"EB", EB}, {"PB", PB}, {"TB", TB}, {"GB", GB}, {"MB", MB}, {"kB", KB},
+	{"B", Byte},
+}
+
+// ParseByteSize parses a number with an optional byte size unit.
+// Units are matched case insensitive.
+func ParseByteSize(str string) (ByteSize, error) {
+	str = strings.TrimSpace(str)
+	if strings.HasPrefix(str, "-") {
+		return 0, fmt.Errorf("negative byte size %q", str)
+	}
+	number := strings.TrimRightFunc(str, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	if number == "" {
+		return 0, fmt.Errorf("invalid byte size %q", str)
+	}
+	unit := strings.TrimSpace(str[len(number):])
+	size := Byte
+	if unit != "" {
+		found := false
+		for _, u := range byteSizeUnits {
+			if strings.EqualFold(unit, u.name) {
+				size = u.size
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid byte size unit %q", unit)
+		}
+	}
+	if i, err := strconv.ParseUint(number, 10, 64); err == nil {
+		if i > uint64(^ByteSize(0)/size) {
+			return 0, fmt.Errorf("byte size %q out of range", str)
+		}
+		return ByteSize(i) * size, nil
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", str)
+	}
+	f *= float64(size)
+	// float64(math.MaxUint64) is rounded up to 1<<64
+	if f >= math.MaxUint64 {
+		return 0, fmt.Errorf("byte size %q out of range", str)
+	}
+	return ByteSize(f), nil
+}
+
+// String formats the size with the largest unit
+// that represents it without fraction.
+func (b ByteSize) String() string {
+	if b == 0 {
+		return "0B"
+	}
+	for _, u := range byteSizeUnits {
+		if b%u.size == 0 {
+			return strconv.FormatUint(uint64(b/u.size), 10) + u.name
+		}
+	}
+	return strconv.FormatUint(uint64(b), 10) + "B"
+}
+
+// Set implements pflag.Value
+func (b *ByteSize) Set(str string) error {
+	size, err := ParseByteSize(str)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	return b.Set(string(text))
+}
+
+// Percent is a percentage where 75 means 75%
+// that implements pflag.Value, encoding.TextMarshaler
+// and encoding.TextUnmarshaler.
+// It is parsed from strings like "75%" or "75".
+type Percent float64
+
+// ParsePercent parses a number with an optional percent sign.
+func ParsePercent(str string) (Percent, error) {
+	number := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(str), "%"))
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percent %q", str)
+	}
+	return Percent(f), nil
+}
+
+// Fraction returns the percentage as fraction of 1,
+// for example 0.75 for 75%.
+func (p Percent) Fraction() float64 {
+	return float64(p) / 100
+}
+
+// String formats the percentage with a percent sign
+func (p Percent) String() string {
+	return strconv.FormatFloat(float64(p), 'f', -1, 64) + "%"
+}
+
+// Set implements pflag.Value
+func (p *Percent) Set(str string) error {
+	percent, err := ParsePercent(str)
+	if err != nil {
+		return err
+	}
+	*p = percent
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (p Percent) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (p *Percent) UnmarshalText(text []byte) error {
+	return p.Set(string(text))
+}
+
+// FileMode is an os.FileMode that implements pflag.Value,
+// encoding.TextMarshaler and encoding.TextUnmarshaler.
+// It is parsed from and formatted as octal number like "0644".
+type FileMode os.FileMode
+
+// ParseFileMode parses an octal file mode like "0644", "644" or "0o644"
+func ParseFileMode(str string) (FileMode, error) {
+	number := strings.TrimSpace(str)
+	number = strings.TrimPrefix(strings.TrimPrefix(number, "0o"), "0O")
+	mode, err := strconv.ParseUint(number, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid octal file mode %q", str)
+	}
+	return FileMode(mode), nil
+}
+
+// FileMode returns the mode as os.FileMode
+func (m FileMode) FileMode() os.FileMode {
+	return os.FileMode(m)
+}
+
+// String formats the mode as octal number with leading zero
+func (m FileMode) String() string {
+	return fmt.Sprintf("%#o", uint32(m))
+}
+
+// Set implements pflag.Value
+func (m *FileMode) Set(str string) error {
+	mode, err := ParseFileMode(str)
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (m FileMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (m *FileMode) UnmarshalText(text []byte) error {
+	return m.Set(string(text))
+}
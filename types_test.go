@@ -0,0 +1,167 @@
+package structflag
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    ByteSize
+		wantErr bool
+	}{
+		{str: "0", want: 0},
+		{str: "1024", want: 1024},
+		{str: "512KiB", want: 512 * KiB},
+		{str: "10MB", want: 10 * MB},
+		{str: "10mb", want: 10 * MB},
+		{str: " 1 GiB ", want: GiB},
+		{str: "1.5KiB", want: 1536},
+		{str: "16EiB", wantErr: true},
+		{str: "18446744073709551615", want: 18446744073709551615},
+		{str: "18446744073709551616", wantErr: true},
+		{str: "1e30", wantErr: true},
+		{str: "20000000000000000000.5", wantErr: true},
+		{str: "-5", wantErr: true},
+		{str: "-1.5KiB", wantErr: true},
+		{str: "", wantErr: true},
+		{str: "KiB", wantErr: true},
+		{str: "10XB", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.str)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseByteSize(%q) = %d, want error", tt.str, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseByteSize(%q) error: %s", tt.str, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", tt.str, got, tt.want)
+		}
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	tests := []struct {
+		size ByteSize
+		want string
+	}{
+		{0, "0B"},
+		{1, "1B"},
+		{1000, "1kB"},
+		{1024, "1KiB"},
+		{1536, "1536B"},
+		{10 * MB, "10MB"},
+		{512 * KiB, "512KiB"},
+	}
+	for _, tt := range tests {
+		if got := tt.size.String(); got != tt.want {
+			t.Errorf("ByteSize(%d).String() = %q, want %q", uint64(tt.size), got, tt.want)
+		}
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    Percent
+		wantErr bool
+	}{
+		{str: "75%", want: 75},
+		{str: "75", want: 75},
+		{str: " 12.5 % ", want: 12.5},
+		{str: "%", wantErr: true},
+		{str: "x%", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePercent(tt.str)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParsePercent(%q) = %v, want error", tt.str, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParsePercent(%q) = %v, %v, want %v", tt.str, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    FileMode
+		wantErr bool
+	}{
+		{str: "0644", want: 0644},
+		{str: "644", want: 0644},
+		{str: "0o755", want: 0755},
+		{str: "0888", wantErr: true},
+		{str: "rw", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFileMode(tt.str)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseFileMode(%q) = %v, want error", tt.str, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseFileMode(%q) = %v, %v, want %v", tt.str, got, err, tt.want)
+		}
+	}
+}
+
+type typesConfig struct {
+	XMLName xml.Name `json:"-" xml:"config"`
+	Size    ByteSize `json:"size" xml:"size"`
+	Share   Percent  `json:"share" xml:"share"`
+	Mode    FileMode `json:"mode" xml:"mode"`
+}
+
+func TestTypesFileRoundTrip(t *testing.T) {
+	want := typesConfig{
+		XMLName: xml.Name{Local: "config"},
+		Size:    512 * KiB,
+		Share:   75,
+		Mode:    0644,
+	}
+	dir := t.TempDir()
+
+	jsonFile := filepath.Join(dir, "config.json")
+	err := SaveJSON(jsonFile, &want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotJSON typesConfig
+	err = LoadJSON(jsonFile, &gotJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJSON.XMLName = want.XMLName
+	if gotJSON != want {
+		t.Errorf("JSON round trip = %+v, want %+v", gotJSON, want)
+	}
+
+	xmlFile := filepath.Join(dir, "config.xml")
+	err = SaveXML(xmlFile, &want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotXML typesConfig
+	err = LoadXML(xmlFile, &gotXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotXML != want {
+		t.Errorf("XML round trip = %+v, want %+v", gotXML, want)
+	}
+}
@@ -0,0 +1,99 @@
+package structflag
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ogier/pflag"
+)
+
+// printDefaults prints the default values of all flags like
+// pflag.FlagSet.PrintDefaults, but shows negatable bools as --[no-]name
+// instead of listing the negated flags separately
+// and leaves out hidden, deprecated and alias flags.
+// Flags that don't support VisitAll print their own defaults.
+func printDefaults(flags Flags) {
+	visitor, ok := flags.(interface{ VisitAll(func(*pflag.Flag)) })
+	if !ok {
+		flags.PrintDefaults()
+		return
+	}
+	visitor.VisitAll(func(flag *pflag.Flag) {
+		switch flag.Value.(type) {
+		case *hiddenValue, *aliasValue:
+			return
+		}
+		name := flag.Name
+		if n := negatableOf(flag.Value); n != nil {
+			if n.negate {
+				return
+			}
+			name = "[" + NegationPrefix + "]" + name
+		}
+		format := "--%s=%s: %s\n"
+		if isStringFlag(flag.Value) {
+			// put quotes on the value
+			format = "--%s=%q: %s\n"
+		}
+		if len(flag.Shorthand) > 0 {
+			format = "  -%s, " + format
+		} else {
+			format = "   %s   " + format
+		}
+		fmt.Fprintf(&flagSetColorOutput, format, flag.Shorthand, name, flag.DefValue, flag.Usage)
+	})
+}
+
+// isStringFlag returns if the flag value is a string
+// to quote it like pflag.FlagSet.PrintDefaults
+func isStringFlag(value pflag.Value) bool {
+	for {
+		switch v := value.(type) {
+		case *sourceValue:
+			value = v.Value
+		case *allocValue:
+			value = v.Value
+		case *secretValue:
+			value = v.Value
+		case *scalarValue:
+			return v.value.Kind() == reflect.String
+		case *enumValue:
+			return v.value.Kind() == reflect.String
+		default:
+			return fmt.Sprintf("%T", value) == "*pflag.stringValue"
+		}
+	}
+}
+
+// negatableOf returns the negatableValue of value
+// if it is one or wrapped by a secretValue or hiddenValue.
+func negatableOf(value pflag.Value) *negatableValue {
+	for {
+		switch v := value.(type) {
+		case *negatableValue:
+			return v
+		case *secretValue:
+			value = v.Value
+		case *hiddenValue:
+			value = v.Value
+		default:
+			return nil
+		}
+	}
+}
+
+// negatableFlagNames returns the names of the negatable
+// bool flags of flags or nil if flags don't support VisitAll.
+func negatableFlagNames(flags Flags) map[string]bool {
+	visitor, ok := flags.(interface{ VisitAll(func(*pflag.Flag)) })
+	if !ok {
+		return nil
+	}
+	names := make(map[string]bool)
+	visitor.VisitAll(func(flag *pflag.Flag) {
+		if n := negatableOf(flag.Value); n != nil && !n.negate {
+			names[flag.Name] = true
+		}
+	})
+	return names
+}
@@ -0,0 +1,105 @@
+package structflag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestPrintDefaults(t *testing.T) {
+	resetFlags(t)
+	var buf bytes.Buffer
+	Output = &buf
+	noColor := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = noColor })
+
+	config := struct {
+		Name     string `usage:"the name"`
+		Color    bool   `default:"true"`
+		Secret   bool   `default:"true" secret:"true"`
+		Password string `secret:"true" default:"pass"`
+		Hidden   string `hidden:"true"`
+		Both     string `hidden:"true" secret:"true"`
+		Old      int    `deprecated:"use --Name"`
+		Alias    int    `flag:"alias,other"`
+	}{Name: "x"}
+	err := StructVarE(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	printDefaults(getOrCreateFlags())
+	usage := buf.String()
+
+	tests := []struct {
+		str  string
+		want bool
+	}{
+		{`--Name="x": the name`, true},
+		{"--[no-]Color=true", true},
+		{"--no-Color", false},
+		{"--[no-]Secret=******", true},
+		{`--Password="******"`, true},
+		{"pass", false},
+		{"--Hidden", false},
+		{"--Both", false},
+		{"--Old", false},
+		{"--alias=0: (aliases: --other)", true},
+		{"--other=", false},
+	}
+	for _, tt := range tests {
+		if got := strings.Contains(usage, tt.str); got != tt.want {
+			t.Errorf("usage contains %q = %t, want %t\n%s", tt.str, got, tt.want, usage)
+		}
+	}
+}
+
+type hiddenConfig struct {
+	Name  string `flag:"name"`
+	Debug string `flag:"debug" hidden:"true"`
+	Old   int    `flag:"old" deprecated:"use --name"`
+	Color bool   `flag:"color" default:"true" deprecated:"colors are always on"`
+}
+
+func TestHiddenAndDeprecatedFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		want       hiddenConfig
+		wantOutput string
+	}{
+		{name: "none", want: hiddenConfig{Color: true}},
+		{name: "hidden", args: []string{"--debug=x"}, want: hiddenConfig{Debug: "x", Color: true}},
+		{
+			name:       "deprecated",
+			args:       []string{"--old=1"},
+			want:       hiddenConfig{Old: 1, Color: true},
+			wantOutput: "Flag --old is deprecated: use --name\n",
+		},
+		{
+			name:       "deprecated negation",
+			args:       []string{"--no-color"},
+			want:       hiddenConfig{},
+			wantOutput: "Flag --no-color is deprecated: colors are always on\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			var buf bytes.Buffer
+			Output = &buf
+			config, _, err := Bind[hiddenConfig](WithArgs(tt.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *config != tt.want {
+				t.Errorf("Bind() = %+v, want %+v", *config, tt.want)
+			}
+			if buf.String() != tt.wantOutput {
+				t.Errorf("output = %q, want %q", buf.String(), tt.wantOutput)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package structflag
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	reflection "github.com/ungerik/go-reflection"
+)
+
+// visitFields calls visit for every exported field of structPtr
+// that is defined as flag by StructVar together with its flag name.
+// Pointer fields are dereferenced and nil pointers are passed
+// as invalid reflect.Value, except nil pointers to nested structs
+// which are skipped.
+// The fields of nested structs are visited instead of the struct field.
+func visitFields(structPtr interface{}, namePrefix string, visit func(field reflect.StructField, value reflect.Value, name string)) {
+	for _, f := range reflection.FlatExportedStructFields(structPtr) {
+		fieldName, ok := fieldFlagName(f.Field)
+		if !ok {
+			continue
+		}
+		fieldValue := f.Value
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				if !isNestedStructType(f.Field.Type.Elem()) {
+					visit(f.Field, reflect.Value{}, NameFunc(namePrefix+fieldName))
+				}
+				continue
+			}
+			fieldValue = fieldValue.Elem()
+		}
+		if isNestedStructType(fieldValue.Type()) {
+			visitFields(fieldValue, namePrefix+nestedNamePrefix(f.Field, fieldName), visit)
+			continue
+		}
+		visit(f.Field, fieldValue, NameFunc(namePrefix+fieldName))
+	}
+}
+
+// isNestedStructType returns if the fields of
+// a struct field of type t are defined as flags.
+func isNestedStructType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct &&
+		t != timeTimeType &&
+		!isTextType(t) &&
+		!reflect.PtrTo(t).Implements(pflagValueType)
+}
+
+// checkEnums returns FieldErrors for all fields of structPtr
+// with an EnumTag and a non zero value that is not one of the enum values.
+// If EnumIgnoreCase is true, then matching values
+// are set to the case of the enum value.
+func checkEnums(structPtr interface{}) error {
+	var errs FieldErrors
+	visitFields(structPtr, "", func(field reflect.StructField, value reflect.Value, name string) {
+		enumStr, hasEnum := field.Tag.Lookup(EnumTag)
+		if !hasEnum || !value.IsValid() || value.IsZero() {
+			return
+		}
+		allowed, err := parseEnum(value.Type(), enumStr)
+		if err != nil {
+			errs = append(errs, newFieldError(field, name, EnumTag, err))
+			return
+		}
+		v, ok := matchEnum(value, allowed)
+		if !ok {
+			err = fmt.Errorf("%v is not one of %s", maskedValue(field, value.Interface()), formatEnum(allowed))
+			errs = append(errs, newFieldError(field, name, EnumTag, err))
+			return
+		}
+		value.Set(v)
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// isRequired returns if field has a RequiredTag with a true value
+func isRequired(field reflect.StructField) (bool, error) {
+	tag, ok := field.Tag.Lookup(RequiredTag)
+	if !ok {
+		return false, nil
+	}
+	return strconv.ParseBool(tag)
+}
+
+// checkRequired returns FieldErrors for all fields of structPtr
+// with a RequiredTag whose flag has no source in sources.
+func checkRequired(structPtr interface{}, sources *valueSources) error {
+	var errs FieldErrors
+	visitFields(structPtr, "", func(field reflect.StructField, value reflect.Value, name string) {
+		required, err := isRequired(field)
+		if err != nil {
+			errs = append(errs, newFieldError(field, name, RequiredTag, err))
+			return
+		}
+		if required && (!value.IsValid() || sources.get(name) == noSource) {
+			errs = append(errs, newFieldError(field, name, RequiredTag, errors.New("missing required flag")))
+		}
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkRelations returns FieldErrors for all fields of structPtr
+// that were set by conflicting negatable flags,
+// together with another field of the same XorTag group
+// or without the fields named in their RequiresTag.
+// Flags with a source in sources count as set.
+func checkRelations(structPtr interface{}, sources *valueSources) error {
+	isSet := make(map[string]bool)
+	visitFields(structPtr, "", func(field reflect.StructField, value reflect.Value, name string) {
+		isSet[name] = value.IsValid() && sources.get(name) != noSource
+	})
+
+	var errs FieldErrors
+	xorSet := make(map[string]string)
+	visitFields(structPtr, "", func(field reflect.StructField, value reflect.Value, name string) {
+		if !isSet[name] {
+			return
+		}
+		if err := sources.conflict(name); err != nil {
+			errs = append(errs, newFieldError(field, name, "", err))
+		}
+		for _, group := range tagList(field, XorTag) {
+			if other, ok := xorSet[group]; ok {
+				err := fmt.Errorf("mutually exclusive with --%s", other)
+				errs = append(errs, newFieldError(field, name, XorTag, err))
+			} else {
+				xorSet[group] = name
+			}
+		}
+		for _, required := range tagList(field, RequiresTag) {
+			set, exists := isSet[required]
+			switch {
+			case !exists:
+				errs = append(errs, newFieldError(field, name, RequiresTag, fmt.Errorf("unknown flag --%s", required)))
+			case !set:
+				errs = append(errs, newFieldError(field, name, RequiresTag, fmt.Errorf("requires --%s", required)))
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkParsed returns the FieldErrors of checkRequired,
+// checkRelations and Validate for a struct after parsing.
+func checkParsed(structPtr interface{}, sources *valueSources) (errs FieldErrors) {
+	if err := checkRequired(structPtr, sources); err != nil {
+		errs = append(errs, err.(FieldErrors)...)
+	}
+	if err := checkRelations(structPtr, sources); err != nil {
+		errs = append(errs, err.(FieldErrors)...)
+	}
+	if err := Validate(structPtr); err != nil {
+		errs = append(errs, err.(FieldErrors)...)
+	}
+	return errs
+}
+
+// Validate checks the values of all fields of structPtr against
+// their EnumTag, MinTag, MaxTag, LenTag, PatternTag and OneOfTag
+// and returns FieldErrors for every failing field.
+// Nil pointer fields are not validated.
+func Validate(structPtr interface{}) error {
+	err := checkEnums(structPtr)
+	errs, _ := err.(FieldErrors)
+	visitFields(structPtr, "", func(field reflect.StructField, value reflect.Value, name string) {
+		if !value.IsValid() {
+			return
+		}
+		for _, tag := range []string{MinTag, MaxTag, LenTag, PatternTag, OneOfTag} {
+			tagValue, ok := field.Tag.Lookup(tag)
+			if !ok {
+				continue
+			}
+			err := validateTag(field, value, tag, tagValue)
+			if err != nil {
+				errs = append(errs, newFieldError(field, name, tag, err))
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateTag(field reflect.StructField, value reflect.Value, tag, tagValue string) error {
+	switch tag {
+	case MinTag, MaxTag:
+		if isLenType(value.Type()) {
+			limit, err := strconv.Atoi(tagValue)
+			if err != nil {
+				return err
+			}
+			if tag == MinTag && value.Len() < limit {
+				return fmt.Errorf("length %d is less than %d", value.Len(), limit)
+			}
+			if tag == MaxTag && value.Len() > limit {
+				return fmt.Errorf("length %d is greater than %d", value.Len(), limit)
+			}
+			return nil
+		}
+		limit, err := parseScalar(value.Type(), tagValue)
+		if err != nil {
+			return err
+		}
+		cmp, err := compareNumbers(value, limit)
+		if err != nil {
+			return err
+		}
+		if tag == MinTag && cmp < 0 {
+			return fmt.Errorf("%v is less than %v", maskedValue(field, value.Interface()), limit.Interface())
+		}
+		if tag == MaxTag && cmp > 0 {
+			return fmt.Errorf("%v is greater than %v", maskedValue(field, value.Interface()), limit.Interface())
+		}
+		return nil
+
+	case LenTag:
+		if !isLenType(value.Type()) {
+			return fmt.Errorf("length not supported for type %s", value.Type())
+		}
+		length, err := strconv.Atoi(tagValue)
+		if err != nil {
+			return err
+		}
+		if value.Len() != length {
+			return fmt.Errorf("length %d is not %d", value.Len(), length)
+		}
+		return nil
+
+	case PatternTag:
+		if value.Kind() != reflect.String {
+			return fmt.Errorf("pattern not supported for type %s", value.Type())
+		}
+		re, err := regexp.Compile(tagValue)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(value.String()) {
+			return fmt.Errorf("%q does not match pattern", maskedValue(field, value.String()))
+		}
+		return nil
+
+	case OneOfTag:
+		allowed, err := parseEnum(value.Type(), tagValue)
+		if err != nil {
+			return err
+		}
+		if _, ok := matchEnum(value, allowed); !ok {
+			return fmt.Errorf("%v is not one of %s", maskedValue(field, value.Interface()), formatEnum(allowed))
+		}
+		return nil
+	}
+	return nil
+}
+
+// isLenType returns if MinTag, MaxTag and LenTag
+// refer to the length of values of type t
+func isLenType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// compareNumbers returns -1, 0 or +1 if a is less than,
+// equal to, or greater than b of the same numeric type.
+func compareNumbers(a, b reflect.Value) (int, error) {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compare(a.Int(), b.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compare(a.Uint(), b.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return compare(a.Float(), b.Float()), nil
+	}
+	return 0, fmt.Errorf("comparison not supported for type %s", a.Type())
+}
+
+func compare[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
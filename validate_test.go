@@ -0,0 +1,112 @@
+package structflag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		tag      string
+		tagValue string
+		wantErr  bool
+	}{
+		{name: "min int", value: 5, tag: MinTag, tagValue: "1"},
+		{name: "min int below", value: 0, tag: MinTag, tagValue: "1", wantErr: true},
+		{name: "max uint16", value: uint16(8080), tag: MaxTag, tagValue: "65000"},
+		{name: "max uint16 above", value: uint16(65001), tag: MaxTag, tagValue: "65000", wantErr: true},
+		{name: "min float", value: 0.5, tag: MinTag, tagValue: "0.25"},
+		{name: "max float above", value: 1.5, tag: MaxTag, tagValue: "1", wantErr: true},
+		{name: "min string length", value: "abc", tag: MinTag, tagValue: "3"},
+		{name: "min string length below", value: "ab", tag: MinTag, tagValue: "3", wantErr: true},
+		{name: "max slice length above", value: []int{1, 2, 3}, tag: MaxTag, tagValue: "2", wantErr: true},
+		{name: "len map", value: map[string]int{"a": 1}, tag: LenTag, tagValue: "1"},
+		{name: "len string", value: "abc", tag: LenTag, tagValue: "2", wantErr: true},
+		{name: "len int", value: 1, tag: LenTag, tagValue: "1", wantErr: true},
+		{name: "pattern", value: "abc-1", tag: PatternTag, tagValue: `^[a-z]+-\d$`},
+		{name: "pattern mismatch", value: "abc", tag: PatternTag, tagValue: `^\d+$`, wantErr: true},
+		{name: "invalid pattern", value: "abc", tag: PatternTag, tagValue: `(`, wantErr: true},
+		{name: "pattern int", value: 1, tag: PatternTag, tagValue: `\d`, wantErr: true},
+		{name: "oneof string", value: "b", tag: OneOfTag, tagValue: "a,b"},
+		{name: "oneof string mismatch", value: "c", tag: OneOfTag, tagValue: "a,b", wantErr: true},
+		{name: "oneof int", value: 2, tag: OneOfTag, tagValue: "1, 2"},
+		{name: "invalid limit", value: 1, tag: MinTag, tagValue: "x", wantErr: true},
+		{name: "min bool", value: true, tag: MinTag, tagValue: "true", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTag(reflect.StructField{Name: "Field"}, reflect.ValueOf(tt.value), tt.tag, tt.tagValue)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateTag(%v, %s:%q) = %v, want error %t", tt.value, tt.tag, tt.tagValue, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type validateDatabase struct {
+	Host string `flag:"host" min:"1"`
+	Port int    `flag:"port" min:"1" max:"65535"`
+}
+
+type validateConfig struct {
+	Name  string            `flag:"name" pattern:"^[a-z]+$"`
+	Mode  string            `flag:"mode" oneof:"fast,slow"`
+	Tags  []string          `flag:"tag" max:"2"`
+	DB    validateDatabase  `flag:"db"`
+	Opt   *validateDatabase `flag:"opt"`
+	Token string            `flag:"token" min:"8" secret:"true"`
+}
+
+func TestValidate(t *testing.T) {
+	valid := validateConfig{
+		Name:  "abc",
+		Mode:  "fast",
+		DB:    validateDatabase{Host: "h", Port: 80},
+		Token: "12345678",
+	}
+	if err := Validate(&valid); err != nil {
+		t.Errorf("Validate() error: %s", err)
+	}
+
+	invalid := validateConfig{
+		Name:  "ABC",
+		Mode:  "medium",
+		Tags:  []string{"a", "b", "c"},
+		DB:    validateDatabase{Port: 70000},
+		Opt:   &validateDatabase{Host: "h"},
+		Token: "secret",
+	}
+	err := Validate(&invalid)
+	errs, ok := err.(FieldErrors)
+	if !ok {
+		t.Fatalf("Validate() = %v, want FieldErrors", err)
+	}
+	want := []struct {
+		flag string
+		tag  string
+	}{
+		{"name", PatternTag},
+		{"mode", OneOfTag},
+		{"tag", MaxTag},
+		{"db.host", MinTag},
+		{"db.port", MaxTag},
+		{"opt.port", MinTag},
+		{"token", MinTag},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Validate() = %d errors, want %d:\n%s", len(errs), len(want), errs)
+	}
+	for i, w := range want {
+		if errs[i].Flag != w.flag || errs[i].Tag != w.tag {
+			t.Errorf("error %d = %s, want flag %s with tag %s", i, errs[i], w.flag, w.tag)
+		}
+	}
+
+	resetFlags(t)
+	_, _, err = Bind[validateDatabase](WithArgs("--host=h", "--port=0"))
+	if errs, ok := err.(FieldErrors); !ok || len(errs) != 1 || errs[0].Flag != "port" {
+		t.Errorf("Bind() = %v, want error for port", err)
+	}
+}
@@ -0,0 +1,609 @@
+package structflag
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ogier/pflag"
+)
+
+// scalarValue implements pflag.Value for a struct field
+// of a scalar kind that has no typed Var method in Flags.
+type scalarValue struct {
+	value reflect.Value
+}
+
+func newScalarValue(value reflect.Value) *scalarValue {
+	return &scalarValue{value: value}
+}
+
+func (s *scalarValue) Set(str string) error {
+	v, err := parseScalar(s.value.Type(), str)
+	if err != nil {
+		return err
+	}
+	s.value.Set(v)
+	return nil
+}
+
+func (s *scalarValue) String() string {
+	if !s.value.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(s.value.Interface())
+}
+
+func (s *scalarValue) IsBoolFlag() bool {
+	return s.value.Kind() == reflect.Bool
+}
+
+// parseScalar parses str as a value of type t.
+// Integer and float values are range checked against the bit size of t.
+func parseScalar(t reflect.Type, str string) (reflect.Value, error) {
+	if t == timeDurationType {
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(d), nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(b).Convert(t), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(str, 0, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(i).Convert(t), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(str, 0, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(u).Convert(t), nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(f).Convert(t), nil
+
+	case reflect.String:
+		return reflect.ValueOf(str).Convert(t), nil
+	}
+	return reflect.Value{}, errUnsupportedType(t)
+}
+
+// isScalarType returns if parseScalar supports values of type t
+func isScalarType(t reflect.Type) bool {
+	if t == timeDurationType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	}
+	return false
+}
+
+// sliceValue implements pflag.Value for slice struct fields.
+// The flag can be repeated and every value can hold
+// multiple comma separated elements.
+type sliceValue struct {
+	value   reflect.Value
+	changed bool
+}
+
+func newSliceValue(value reflect.Value) *sliceValue {
+	return &sliceValue{value: value}
+}
+
+func (s *sliceValue) Set(str string) error {
+	elems, err := parseSlice(s.value.Type(), str)
+	if err != nil {
+		return err
+	}
+	if !s.changed && !AppendSliceFlags {
+		s.value.Set(reflect.MakeSlice(s.value.Type(), 0, elems.Len()))
+	}
+	s.value.Set(reflect.AppendSlice(s.value, elems))
+	s.changed = true
+	return nil
+}
+
+func (s *sliceValue) String() string {
+	if !s.value.IsValid() {
+		return ""
+	}
+	elems := make([]string, s.value.Len())
+	for i := range elems {
+		elems[i] = fmt.Sprint(s.value.Index(i).Interface())
+	}
+	return strings.Join(elems, ",")
+}
+
+// parseSlice parses the comma separated elements of str
+// as a slice of type t.
+func parseSlice(t reflect.Type, str string) (reflect.Value, error) {
+	if str == "" {
+		return reflect.MakeSlice(t, 0, 0), nil
+	}
+	parts := strings.Split(str, ",")
+	slice := reflect.MakeSlice(t, len(parts), len(parts))
+	for i, part := range parts {
+		elem, err := parseScalar(t.Elem(), strings.TrimSpace(part))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		slice.Index(i).Set(elem)
+	}
+	return slice, nil
+}
+
+// mapValue implements pflag.Value for map struct fields.
+// The flag can be repeated and every value can hold
+// multiple comma separated key=value pairs.
+type mapValue struct {
+	value   reflect.Value
+	changed bool
+}
+
+func newMapValue(value reflect.Value) *mapValue {
+	return &mapValue{value: value}
+}
+
+func (m *mapValue) Set(str string) error {
+	pairs, err := parseMap(m.value.Type(), str)
+	if err != nil {
+		return err
+	}
+	if m.value.IsNil() || (!m.changed && !AppendSliceFlags) {
+		m.value.Set(reflect.MakeMapWithSize(m.value.Type(), pairs.Len()))
+	}
+	iter := pairs.MapRange()
+	for iter.Next() {
+		m.value.SetMapIndex(iter.Key(), iter.Value())
+	}
+	m.changed = true
+	return nil
+}
+
+func (m *mapValue) String() string {
+	if !m.value.IsValid() {
+		return ""
+	}
+	pairs := make([]string, 0, m.value.Len())
+	iter := m.value.MapRange()
+	for iter.Next() {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", iter.Key().Interface(), iter.Value().Interface()))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// isMapType returns if parseMap supports values of type t
+func isMapType(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && isScalarType(t.Key()) && isScalarType(t.Elem())
+}
+
+// parseMap parses the comma separated key=value pairs of str
+// as a map of type t.
+func parseMap(t reflect.Type, str string) (reflect.Value, error) {
+	if str == "" {
+		return reflect.MakeMap(t), nil
+	}
+	parts := strings.Split(str, ",")
+	m := reflect.MakeMapWithSize(t, len(parts))
+	for _, part := range parts {
+		keyStr, valStr, found := strings.Cut(part, "=")
+		if !found {
+			return reflect.Value{}, fmt.Errorf("missing '=' in map entry %q", part)
+		}
+		key, err := parseScalar(t.Key(), strings.TrimSpace(keyStr))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		val, err := parseScalar(t.Elem(), strings.TrimSpace(valStr))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		m.SetMapIndex(key, val)
+	}
+	return m, nil
+}
+
+// textValue implements pflag.Value for struct fields
+// that implement encoding.TextUnmarshaler.
+// If the field also implements encoding.TextMarshaler,
+// then it is used to format the value.
+type textValue struct {
+	value reflect.Value
+}
+
+func newTextValue(value reflect.Value) *textValue {
+	return &textValue{value: value}
+}
+
+func (t *textValue) Set(str string) error {
+	return t.value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+}
+
+func (t *textValue) String() string {
+	if !t.value.IsValid() {
+		return ""
+	}
+	if m, ok := t.value.Addr().Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	}
+	return fmt.Sprint(t.value.Interface())
+}
+
+// isTextType returns if pointers to type t
+// implement encoding.TextUnmarshaler
+func isTextType(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// timeValue implements pflag.Value for time.Time struct fields.
+// Values are parsed with layout in location,
+// or relative to the current time as "now" or a duration like "-24h".
+type timeValue struct {
+	value    reflect.Value
+	layout   string
+	location *time.Location
+}
+
+func newTimeValue(value reflect.Value, layout string, location *time.Location) *timeValue {
+	return &timeValue{value: value, layout: layout, location: location}
+}
+
+func (t *timeValue) Set(str string) error {
+	v, err := parseTime(str, t.layout, t.location)
+	if err != nil {
+		return err
+	}
+	t.value.Set(reflect.ValueOf(v))
+	return nil
+}
+
+func (t *timeValue) String() string {
+	if !t.value.IsValid() {
+		return ""
+	}
+	v := t.value.Interface().(time.Time)
+	if v.IsZero() {
+		return ""
+	}
+	return v.Format(t.layout)
+}
+
+// parseTime parses str with layout in location.
+// The string "now" returns the current time
+// and a duration string returns the current time plus that duration.
+func parseTime(str, layout string, location *time.Location) (time.Time, error) {
+	if str == "now" {
+		return time.Now().In(location), nil
+	}
+	if d, err := time.ParseDuration(str); err == nil {
+		return time.Now().In(location).Add(d), nil
+	}
+	return time.ParseInLocation(layout, str, location)
+}
+
+// allocValue wraps the pflag.Value of a field in lazily allocated memory
+// and calls alloc after the value has been set.
+type allocValue struct {
+	pflag.Value
+	alloc func()
+}
+
+func (a *allocValue) Set(str string) error {
+	err := a.Value.Set(str)
+	if err != nil {
+		return err
+	}
+	a.alloc()
+	return nil
+}
+
+func (a *allocValue) IsBoolFlag() bool {
+	return isBoolFlag(a.Value)
+}
+
+// isBoolFlag returns if value is a bool flag
+// that can be set without a value, like --foo.
+// Wrappers of a pflag.Value forward IsBoolFlag with it.
+func isBoolFlag(value pflag.Value) bool {
+	b, ok := value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// allocOnSet returns a new pointer for the nil pointer field ptr
+// and a function that first calls parentAlloc if not nil
+// and then sets ptr to the new pointer if ptr is still nil.
+func allocOnSet(ptr reflect.Value, parentAlloc func()) (newPtr reflect.Value, alloc func()) {
+	newPtr = reflect.New(ptr.Type().Elem())
+	return newPtr, func() {
+		if parentAlloc != nil {
+			parentAlloc()
+		}
+		if ptr.IsNil() {
+			ptr.Set(newPtr)
+		}
+	}
+}
+
+// enumValue implements pflag.Value for string and integer
+// struct fields that only allow the values of an EnumTag.
+type enumValue struct {
+	value   reflect.Value
+	allowed []reflect.Value
+}
+
+func newEnumValue(value reflect.Value, allowed []reflect.Value) *enumValue {
+	return &enumValue{value: value, allowed: allowed}
+}
+
+func (e *enumValue) Set(str string) error {
+	v, err := parseScalar(e.value.Type(), str)
+	if err != nil {
+		return err
+	}
+	v, ok := matchEnum(v, e.allowed)
+	if !ok {
+		return fmt.Errorf("%q is not one of %s", str, formatEnum(e.allowed))
+	}
+	e.value.Set(v)
+	return nil
+}
+
+func (e *enumValue) String() string {
+	if !e.value.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(e.value.Interface())
+}
+
+// isEnumType returns if an EnumTag is supported for type t
+func isEnumType(t reflect.Type) bool {
+	if t == timeDurationType {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// parseEnum parses the comma separated values of an EnumTag as type t
+func parseEnum(t reflect.Type, tag string) ([]reflect.Value, error) {
+	if !isEnumType(t) {
+		return nil, fmt.Errorf("enum not supported for type %s", t)
+	}
+	parts := strings.Split(tag, ",")
+	allowed := make([]reflect.Value, len(parts))
+	for i, part := range parts {
+		v, err := parseScalar(t, strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		allowed[i] = v
+	}
+	return allowed, nil
+}
+
+// matchEnum returns the allowed value matching v.
+// Strings are compared case insensitive if EnumIgnoreCase is true.
+func matchEnum(v reflect.Value, allowed []reflect.Value) (reflect.Value, bool) {
+	for _, a := range allowed {
+		if v.Kind() == reflect.String && EnumIgnoreCase {
+			if strings.EqualFold(v.String(), a.String()) {
+				return a, true
+			}
+		} else if v.Interface() == a.Interface() {
+			return a, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func formatEnum(allowed []reflect.Value) string {
+	strs := make([]string, len(allowed))
+	for i, a := range allowed {
+		strs[i] = fmt.Sprint(a.Interface())
+	}
+	return strings.Join(strs, ", ")
+}
+
+// counterValue implements pflag.Value for integer struct fields
+// that count how often a flag is set, like -v -v -v or -vvv.
+// It is a boolean flag so that it can be set without value,
+// which increments the count. Setting a number sets the count.
+type counterValue struct {
+	value reflect.Value
+}
+
+func newCounterValue(value reflect.Value) *counterValue {
+	return &counterValue{value: value}
+}
+
+func (c *counterValue) Set(str string) error {
+	if str != "true" {
+		v, err := parseScalar(c.value.Type(), str)
+		if err != nil {
+			return err
+		}
+		c.value.Set(v)
+		return nil
+	}
+	switch c.value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.value.SetInt(c.value.Int() + 1)
+	default:
+		c.value.SetUint(c.value.Uint() + 1)
+	}
+	return nil
+}
+
+func (c *counterValue) String() string {
+	if !c.value.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(c.value.Interface())
+}
+
+func (c *counterValue) IsBoolFlag() bool {
+	return true
+}
+
+// isCounterType returns if a CountTag is supported for type t
+func isCounterType(t reflect.Type) bool {
+	return isEnumType(t) && t.Kind() != reflect.String
+}
+
+// negation is shared by the positive and the negated flag of a bool field
+type negation struct {
+	value       pflag.Value
+	sources     *valueSources
+	name        string
+	positiveSet bool
+	negativeSet bool
+}
+
+// negatableValue implements pflag.Value for the positive
+// or the negated flag of a bool field, like --foo and --no-foo.
+type negatableValue struct {
+	*negation
+	negate bool
+}
+
+func newNegatableValues(value pflag.Value, sources *valueSources, name string) (positive, negative *negatableValue) {
+	n := &negation{value: value, sources: sources, name: name}
+	return &negatableValue{n, false}, &negatableValue{n, true}
+}
+
+func (n *negatableValue) Set(str string) error {
+	b, err := strconv.ParseBool(str)
+	if err != nil {
+		return err
+	}
+	if n.negate {
+		n.negativeSet = true
+	} else {
+		n.positiveSet = true
+	}
+	if n.positiveSet && n.negativeSet {
+		// Also remember the conflict for checkRelations
+		// because pflag ignores errors of bool flags without value
+		err = fmt.Errorf("--%s conflicts with --%s%s", n.name, NegationPrefix, n.name)
+		n.sources.setConflict(n.name, err)
+		return err
+	}
+	return n.value.Set(strconv.FormatBool(b != n.negate))
+}
+
+func (n *negatableValue) String() string {
+	if n.negate {
+		return ""
+	}
+	return n.value.String()
+}
+
+func (n *negatableValue) IsBoolFlag() bool {
+	return true
+}
+
+// hiddenValue wraps the pflag.Value of a flag
+// that is not shown in the usage output.
+// If deprecated is not empty, then a warning
+// is printed to Output when the flag is set.
+type hiddenValue struct {
+	pflag.Value
+	name       string
+	deprecated string
+}
+
+func (h *hiddenValue) Set(str string) error {
+	if h.deprecated != "" {
+		fmt.Fprintf(Output, "Flag --%s is deprecated: %s\n", h.name, h.deprecated)
+	}
+	return h.Value.Set(str)
+}
+
+func (h *hiddenValue) IsBoolFlag() bool {
+	return isBoolFlag(h.Value)
+}
+
+// secretValue wraps the pflag.Value of a secret field
+// and returns SecretMask instead of a non empty value
+// so that the value is not shown in the usage output.
+type secretValue struct {
+	pflag.Value
+}
+
+// Set masks the value in the error message of the wrapped Set
+func (s *secretValue) Set(str string) error {
+	err := s.Value.Set(str)
+	if err == nil || str == "" {
+		return err
+	}
+	msg := strings.ReplaceAll(err.Error(), strconv.Quote(str), strconv.Quote(SecretMask))
+	if strings.Contains(msg, str) {
+		// Don't risk leaking the value in an unknown format
+		msg = "invalid value " + strconv.Quote(SecretMask)
+	}
+	return errors.New(msg)
+}
+
+func (s *secretValue) String() string {
+	if s.Value.String() == "" {
+		return ""
+	}
+	return SecretMask
+}
+
+func (s *secretValue) IsBoolFlag() bool {
+	return isBoolFlag(s.Value)
+}
+
+// isSecretValue returns if value is a secretValue
+// or wraps one with a hiddenValue or aliasValue.
+func isSecretValue(value pflag.Value) bool {
+	for {
+		switch v := value.(type) {
+		case *secretValue:
+			return true
+		case *hiddenValue:
+			value = v.Value
+		case *aliasValue:
+			value = v.Value
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,675 @@
+package structflag
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ogier/pflag"
+)
+
+type negatableConfig struct {
+	Color   bool `flag:"color" json:"color" default:"true"`
+	Verbose bool `flag:"verbose" json:"verbose"`
+	Cache   bool `flag:"cache" json:"cache" negatable:"true"`
+	Strict  bool `flag:"strict" json:"strict" default:"true" negatable:"false"`
+}
+
+func TestNegatableFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    negatableConfig
+		wantErr bool
+	}{
+		{name: "defaults", want: negatableConfig{Color: true, Strict: true}},
+		{name: "negated", args: []string{"--no-color", "--no-cache"}, want: negatableConfig{Strict: true}},
+		{name: "positive", args: []string{"--cache", "--verbose"}, want: negatableConfig{Color: true, Verbose: true, Cache: true, Strict: true}},
+		{name: "explicit value", args: []string{"--color=false", "--strict=false"}, want: negatableConfig{}},
+		{name: "conflict", args: []string{"--color", "--no-color"}, wantErr: true},
+		{name: "not negatable without true default", args: []string{"--no-verbose"}, wantErr: true},
+		{name: "not negatable by tag", args: []string{"--no-strict"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			config, _, err := Bind[negatableConfig](WithArgs(tt.args...))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Bind() = %+v, want error", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *config != tt.want {
+				t.Errorf("Bind() = %+v, want %+v", *config, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegatableFlagsAfterLoadingFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(filename, []byte(`{"color": false}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resetFlags(t)
+	bound, _, err := Bind[negatableConfig](WithFile(filename), WithArgs("--no-color"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bound.Color {
+		t.Error("Bind() with --no-color: Color = true")
+	}
+
+	// Negatable without tags because the field is true before loading
+	resetFlags(t)
+	config := struct {
+		Color bool `flag:"color" json:"color"`
+	}{Color: true}
+	err = StructVarE(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadFileAndParse(filename, &config, []string{"--no-color"}, getOrCreateFlags())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Color {
+		t.Error("loadFileAndParse() with --no-color: Color = true")
+	}
+}
+
+func TestIsBoolFlag(t *testing.T) {
+	var (
+		b bool
+		i int
+	)
+	boolValue := newScalarValue(reflect.ValueOf(&b).Elem())
+	intValue := newScalarValue(reflect.ValueOf(&i).Elem())
+	tests := []struct {
+		name  string
+		value pflag.Value
+		want  bool
+	}{
+		{"bool", boolValue, true},
+		{"int", intValue, false},
+		{"counter", newCounterValue(reflect.ValueOf(&i).Elem()), true},
+		{"source", &sourceValue{Value: boolValue}, true},
+		{"alloc", &allocValue{Value: boolValue}, true},
+		{"hidden", &hiddenValue{Value: boolValue}, true},
+		{"secret", &secretValue{boolValue}, true},
+		{"alias", &aliasValue{boolValue}, true},
+		{"nested", &hiddenValue{Value: &secretValue{&sourceValue{Value: boolValue}}}, true},
+		{"nested int", &hiddenValue{Value: &secretValue{&sourceValue{Value: intValue}}}, false},
+	}
+	for _, tt := range tests {
+		if got := isBoolFlag(tt.value); got != tt.want {
+			t.Errorf("isBoolFlag(%s) = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseSlice(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     reflect.Type
+		str     string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "strings", typ: reflect.TypeOf([]string{}), str: "a, b,c", want: []string{"a", "b", "c"}},
+		{name: "empty", typ: reflect.TypeOf([]string{}), str: "", want: []string{}},
+		{name: "ints", typ: reflect.TypeOf([]int{}), str: "1,-2,0x10", want: []int{1, -2, 16}},
+		{name: "durations", typ: reflect.TypeOf([]time.Duration{}), str: "1s,2m", want: []time.Duration{time.Second, 2 * time.Minute}},
+		{name: "uint8 out of range", typ: reflect.TypeOf([]uint8{}), str: "1,256", wantErr: true},
+		{name: "invalid int", typ: reflect.TypeOf([]int{}), str: "1,x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSlice(tt.typ, tt.str)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseSlice() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Interface(), tt.want) {
+				t.Errorf("parseSlice() = %#v, want %#v", got.Interface(), tt.want)
+			}
+		})
+	}
+}
+
+type sliceConfig struct {
+	Hosts []string `flag:"host" json:"hosts" default:"a,b"`
+	Ports []int    `flag:"port" json:"ports"`
+}
+
+func TestSliceFlags(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(filename, []byte(`{"hosts": ["f"], "ports": [1]}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		file   string
+		args   []string
+		append bool
+		want   sliceConfig
+	}{
+		{name: "default", want: sliceConfig{Hosts: []string{"a", "b"}}},
+		{name: "repeated", args: []string{"--host=x", "--host=y"}, want: sliceConfig{Hosts: []string{"x", "y"}}},
+		{name: "comma separated", args: []string{"--host=x,y", "--port=1,2"}, want: sliceConfig{Hosts: []string{"x", "y"}, Ports: []int{1, 2}}},
+		{name: "file", file: filename, want: sliceConfig{Hosts: []string{"f"}, Ports: []int{1}}},
+		{name: "flags replace file", file: filename, args: []string{"--host=x", "--host=y"}, want: sliceConfig{Hosts: []string{"x", "y"}, Ports: []int{1}}},
+		{name: "flags append to file", file: filename, args: []string{"--host=x", "--port=2"}, append: true, want: sliceConfig{Hosts: []string{"f", "x"}, Ports: []int{1, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			AppendSliceFlags = tt.append
+			defer func() { AppendSliceFlags = false }()
+			options := []BindOption{WithArgs(tt.args...)}
+			if tt.file != "" {
+				options = append(options, WithFile(tt.file))
+			}
+			config, _, err := Bind[sliceConfig](options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*config, tt.want) {
+				t.Errorf("Bind() = %+v, want %+v", *config, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     reflect.Type
+		str     string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "strings", typ: reflect.TypeOf(map[string]string{}), str: "env=prod, team = core", want: map[string]string{"env": "prod", "team": "core"}},
+		{name: "empty", typ: reflect.TypeOf(map[string]string{}), str: "", want: map[string]string{}},
+		{name: "empty value", typ: reflect.TypeOf(map[string]string{}), str: "a=", want: map[string]string{"a": ""}},
+		{name: "ints", typ: reflect.TypeOf(map[string]int{}), str: "a=1,b=-2", want: map[string]int{"a": 1, "b": -2}},
+		{name: "int keys", typ: reflect.TypeOf(map[int]bool{}), str: "1=true,2=false", want: map[int]bool{1: true, 2: false}},
+		{name: "durations", typ: reflect.TypeOf(map[string]time.Duration{}), str: "read=1s", want: map[string]time.Duration{"read": time.Second}},
+		{name: "missing equal sign", typ: reflect.TypeOf(map[string]string{}), str: "a", wantErr: true},
+		{name: "invalid value", typ: reflect.TypeOf(map[string]int{}), str: "a=x", wantErr: true},
+		{name: "invalid key", typ: reflect.TypeOf(map[uint8]string{}), str: "256=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMap(tt.typ, tt.str)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseMap() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Interface(), tt.want) {
+				t.Errorf("parseMap() = %#v, want %#v", got.Interface(), tt.want)
+			}
+		})
+	}
+}
+
+type mapConfig struct {
+	Labels map[string]string `flag:"label" json:"labels" default:"env=dev"`
+	Limits map[string]int    `flag:"limit" json:"limits"`
+}
+
+func TestMapFlags(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(filename, []byte(`{"labels": {"team": "core"}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		file   string
+		args   []string
+		append bool
+		want   mapConfig
+	}{
+		{name: "default", want: mapConfig{Labels: map[string]string{"env": "dev"}}},
+		{
+			name: "repeated",
+			args: []string{"--label=env=prod", "--label=team=core,app=x", "--limit=cpu=2"},
+			want: mapConfig{Labels: map[string]string{"env": "prod", "team": "core", "app": "x"}, Limits: map[string]int{"cpu": 2}},
+		},
+		{name: "flags replace file", file: filename, args: []string{"--label=env=prod"}, want: mapConfig{Labels: map[string]string{"env": "prod"}}},
+		{name: "flags add to file", file: filename, args: []string{"--label=env=prod"}, append: true, want: mapConfig{Labels: map[string]string{"env": "prod", "team": "core"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			AppendSliceFlags = tt.append
+			defer func() { AppendSliceFlags = false }()
+			options := []BindOption{WithArgs(tt.args...)}
+			if tt.file != "" {
+				options = append(options, WithFile(tt.file))
+			}
+			config, _, err := Bind[mapConfig](options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*config, tt.want) {
+				t.Errorf("Bind() = %+v, want %+v", *config, tt.want)
+			}
+		})
+	}
+}
+
+type textConfig struct {
+	IP   net.IP     `flag:"ip" default:"127.0.0.1"`
+	Addr netip.Addr `flag:"addr"`
+	Size ByteSize   `flag:"size"`
+}
+
+func TestTextUnmarshalerFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    textConfig
+		wantErr bool
+	}{
+		{
+			name: "default",
+			want: textConfig{IP: net.ParseIP("127.0.0.1")},
+		},
+		{
+			name: "flags",
+			args: []string{"--ip=::1", "--addr=10.0.0.1", "--size=1KiB"},
+			want: textConfig{IP: net.ParseIP("::1"), Addr: netip.MustParseAddr("10.0.0.1"), Size: KiB},
+		},
+		{name: "invalid ip", args: []string{"--ip=x"}, wantErr: true},
+		{name: "invalid addr", args: []string{"--addr=10.0.0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			config, _, err := Bind[textConfig](WithArgs(tt.args...))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Bind() = %+v, want error", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !config.IP.Equal(tt.want.IP) || config.Addr != tt.want.Addr || config.Size != tt.want.Size {
+				t.Errorf("Bind() = %+v, want %+v", *config, tt.want)
+			}
+		})
+	}
+
+	resetFlags(t)
+	var config textConfig
+	err := StructVarE(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lookupFlag(t, "ip").DefValue; got != "127.0.0.1" {
+		t.Errorf("default of --ip = %q, want %q", got, "127.0.0.1")
+	}
+
+	resetFlags(t)
+	invalid := struct {
+		IP net.IP `default:"x"`
+	}{}
+	err = StructVarE(&invalid)
+	if errs, ok := err.(FieldErrors); !ok || len(errs) != 1 || errs[0].Tag != DefaultTag {
+		t.Errorf("StructVarE() with invalid default = %v, want FieldErrors for %s", err, DefaultTag)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	vienna, err := time.LoadLocation("Europe/Vienna")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		name     string
+		str      string
+		layout   string
+		location *time.Location
+		want     time.Time
+		relative time.Duration
+		wantErr  bool
+	}{
+		{name: "RFC3339", str: "2024-01-02T03:04:05Z", layout: time.RFC3339, location: time.UTC, want: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{name: "date in UTC", str: "2024-01-02", layout: "2006-01-02", location: time.UTC, want: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "date in location", str: "2024-01-02", layout: "2006-01-02", location: vienna, want: time.Date(2024, 1, 2, 0, 0, 0, 0, vienna)},
+		{name: "now", str: "now", layout: time.RFC3339, location: time.UTC},
+		{name: "relative", str: "-24h", layout: time.RFC3339, location: time.UTC, relative: -24 * time.Hour},
+		{name: "wrong layout", str: "2024-01-02", layout: time.RFC3339, location: time.UTC, wantErr: true},
+		{name: "invalid", str: "yesterday", layout: time.RFC3339, location: time.UTC, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTime(tt.str, tt.layout, tt.location)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseTime() = %s, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want.IsZero() {
+				// Relative to the current time
+				if d := time.Until(got) - tt.relative; d < -time.Minute || d > time.Minute {
+					t.Errorf("parseTime() = %s, want now %+v", got, tt.relative)
+				}
+				return
+			}
+			if !got.Equal(tt.want) || got.Location() != tt.location {
+				t.Errorf("parseTime() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeFlags(t *testing.T) {
+	resetFlags(t)
+	config := struct {
+		Since time.Time `flag:"since" layout:"2006-01-02" location:"UTC" default:"2024-01-02"`
+		Until time.Time `flag:"until"`
+	}{}
+	err := StructVarE(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC); !config.Since.Equal(want) {
+		t.Errorf("StructVarE() Since = %s, want %s", config.Since, want)
+	}
+	since := lookupFlag(t, "since")
+	if since.DefValue != "2024-01-02" || !strings.Contains(since.Usage, "(layout: 2006-01-02)") {
+		t.Errorf("--since default %q and usage %q", since.DefValue, since.Usage)
+	}
+	_, err = Parse("--until=2024-02-03T04:05:06Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC); !config.Until.Equal(want) {
+		t.Errorf("Parse() Until = %s, want %s", config.Until, want)
+	}
+
+	resetFlags(t)
+	invalid := struct {
+		Since time.Time `location:"Nowhere/City"`
+	}{}
+	err = StructVarE(&invalid)
+	if errs, ok := err.(FieldErrors); !ok || len(errs) != 1 || errs[0].Tag != LocationTag {
+		t.Errorf("StructVarE() with invalid location = %v, want FieldErrors for %s", err, LocationTag)
+	}
+}
+
+type enumConfig struct {
+	Level string `flag:"level" json:"level" enum:"debug, info,warn" default:"info"`
+	Code  int    `flag:"code" json:"code" enum:"0,1,2"`
+}
+
+func TestEnumFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		file       string
+		ignoreCase bool
+		want       enumConfig
+		wantErr    bool
+	}{
+		{name: "default", want: enumConfig{Level: "info"}},
+		{name: "flags", args: []string{"--level=debug", "--code=2"}, want: enumConfig{Level: "debug", Code: 2}},
+		{name: "invalid string", args: []string{"--level=trace"}, wantErr: true},
+		{name: "invalid int", args: []string{"--code=3"}, wantErr: true},
+		{name: "case sensitive", args: []string{"--level=DEBUG"}, wantErr: true},
+		{name: "ignore case", args: []string{"--level=DEBUG"}, ignoreCase: true, want: enumConfig{Level: "debug"}},
+		{name: "file", file: `{"level": "warn", "code": 1}`, want: enumConfig{Level: "warn", Code: 1}},
+		{name: "invalid file", file: `{"level": "trace"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			EnumIgnoreCase = tt.ignoreCase
+			defer func() { EnumIgnoreCase = false }()
+			options := []BindOption{WithArgs(tt.args...)}
+			if tt.file != "" {
+				filename := filepath.Join(t.TempDir(), "config.json")
+				err := os.WriteFile(filename, []byte(tt.file), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+				options = append(options, WithFile(filename))
+			}
+			config, _, err := Bind[enumConfig](options...)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Bind() = %+v, want error", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *config != tt.want {
+				t.Errorf("Bind() = %+v, want %+v", *config, tt.want)
+			}
+		})
+	}
+
+	resetFlags(t)
+	var config enumConfig
+	err := StructVarE(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := lookupFlag(t, "level").Usage; usage != "(one of: debug, info, warn)" {
+		t.Errorf("usage of --level = %q", usage)
+	}
+
+	invalid := []struct {
+		name      string
+		structPtr interface{}
+	}{
+		{"unsupported type", &struct {
+			Ratio float64 `enum:"0.5,1"`
+		}{}},
+		{"invalid value", &struct {
+			Code int `enum:"1,x"`
+		}{}},
+		{"default not allowed", &struct {
+			Level string `enum:"a,b" default:"c"`
+		}{}},
+	}
+	for _, tt := range invalid {
+		resetFlags(t)
+		if err := StructVarE(tt.structPtr); err == nil {
+			t.Errorf("StructVarE() with %s, want error", tt.name)
+		}
+	}
+}
+
+type counterConfig struct {
+	Verbose int   `flag:"verbose" short:"v" count:"true"`
+	Level   uint8 `flag:"level" count:"true" default:"1"`
+}
+
+func TestCounterFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    counterConfig
+		wantErr bool
+	}{
+		{name: "default", want: counterConfig{Level: 1}},
+		{name: "repeated", args: []string{"-v", "-v", "--level"}, want: counterConfig{Verbose: 2, Level: 2}},
+		{name: "combined shorthand", args: []string{"-vvv"}, want: counterConfig{Verbose: 3, Level: 1}},
+		{name: "long", args: []string{"--verbose", "--verbose"}, want: counterConfig{Verbose: 2, Level: 1}},
+		{name: "number", args: []string{"--verbose=5", "-v"}, want: counterConfig{Verbose: 6, Level: 1}},
+		{name: "invalid number", args: []string{"--verbose=x"}, wantErr: true},
+		{name: "overflow", args: []string{"--level=256"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			config, _, err := Bind[counterConfig](WithArgs(tt.args...))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Bind() = %+v, want error", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *config != tt.want {
+				t.Errorf("Bind() = %+v, want %+v", *config, tt.want)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name      string
+		structPtr interface{}
+	}{
+		{"string", &struct {
+			V string `count:"true"`
+		}{}},
+		{"float", &struct {
+			V float64 `count:"true"`
+		}{}},
+		{"invalid tag", &struct {
+			V int `count:"x"`
+		}{}},
+	}
+	for _, tt := range invalid {
+		resetFlags(t)
+		if err := StructVarE(tt.structPtr); err == nil {
+			t.Errorf("StructVarE() with %s count, want error", tt.name)
+		}
+	}
+}
+
+type secretConfig struct {
+	Token string `flag:"token" json:"token" secret:"true" pattern:"^tk_"`
+	PIN   int    `flag:"pin" json:"pin" secret:"true" min:"1000" env:"STRUCTFLAG_TEST_PIN"`
+	Level string `flag:"level" json:"level" secret:"true" enum:"a,b"`
+	Mode  string `flag:"mode" json:"mode" secret:"true" oneof:"x,y"`
+}
+
+// TestSecretMasking checks the errors of invalid secret values.
+// Invalid secret flag values are covered by TestSecretValueSet,
+// because pflag adds the unmasked argument to its parse errors.
+func TestSecretMasking(t *testing.T) {
+	const secret = "s3cr3t"
+	tests := []struct {
+		name   string
+		args   []string
+		env    string
+		file   string
+		data   string
+		secret string
+	}{
+		{name: "flag pattern", args: []string{"--token=" + secret}},
+		{name: "flag min", args: []string{"--pin=777"}, secret: "777"},
+		{name: "flag oneof", args: []string{"--mode=" + secret}},
+		{name: "env", env: secret},
+		{name: "JSON file", file: "config.json", data: `{"token": "` + secret + `"}`},
+		{name: "JSON file enum", file: "config.json", data: `{"level": "` + secret + `"}`},
+		{name: "INI file", file: "config.ini", data: "pin = " + secret + "\n"},
+		{name: "dotenv file", file: "config.env", data: "PIN=" + secret + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(t)
+			if tt.secret == "" {
+				tt.secret = secret
+			}
+			if tt.env != "" {
+				t.Setenv("STRUCTFLAG_TEST_PIN", tt.env)
+			}
+			options := []BindOption{WithArgs(tt.args...)}
+			if tt.file != "" {
+				filename := filepath.Join(t.TempDir(), tt.file)
+				err := os.WriteFile(filename, []byte(tt.data), 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+				options = append(options, WithFile(filename))
+			}
+			_, _, err := Bind[secretConfig](options...)
+			if err == nil {
+				t.Fatal("Bind() want error")
+			}
+			if strings.Contains(err.Error(), tt.secret) || !strings.Contains(err.Error(), SecretMask) {
+				t.Errorf("Bind() error does not mask the secret: %s", err)
+			}
+		})
+	}
+}
+
+func TestSecretValueSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   pflag.Value
+		str     string
+		want    string
+		wantErr string
+	}{
+		{name: "valid", value: newScalarValue(reflect.ValueOf(new(int)).Elem()), str: "5", want: SecretMask},
+		{name: "empty", value: newScalarValue(reflect.ValueOf(new(string)).Elem()), str: "", want: ""},
+		{name: "quoted value in error", value: newScalarValue(reflect.ValueOf(new(int)).Elem()), str: "x1", wantErr: `"` + SecretMask + `"`},
+		{name: "enum", value: newEnumValue(reflect.ValueOf(new(string)).Elem(), []reflect.Value{reflect.ValueOf("a")}), str: "x1", wantErr: `"` + SecretMask + `" is not one of a`},
+		{name: "unquoted value in error", value: unquotedErrorValue{}, str: "x1", wantErr: `invalid value "` + SecretMask + `"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := &secretValue{tt.value}
+			err := value.Set(tt.str)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || strings.Contains(err.Error(), tt.str) {
+					t.Errorf("Set(%q) = %v, want error containing %s", tt.str, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := value.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// unquotedErrorValue returns errors containing the unquoted value
+type unquotedErrorValue struct{}
+
+func (unquotedErrorValue) Set(str string) error { return errors.New("bad " + str) }
+func (unquotedErrorValue) String() string       { return "" }